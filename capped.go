@@ -0,0 +1,120 @@
+package commoncrud
+
+import (
+	"context"
+
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+// WithMaxSize caps the sorted set at maxSize members in pagination order.
+// Members past the cap are trimmed after every insert and the set is no
+// longer settled, so FetchLinked seeds the pages past the cached window from
+// the database, see PastCachedWindow.
+func (pg *PaginationType[T]) WithMaxSize(maxSize int64) *PaginationType[T] {
+	pg.maxSize = maxSize
+	return pg
+}
+
+// trimSortedSet removes the members past maxSize from the sorted set of key.
+func (pg *PaginationType[T]) trimSortedSet(key string) *types.PaginationError {
+	if pg.maxSize <= 0 {
+		return nil
+	}
+
+	var trim *redis.IntCmd
+	if pg.direction == ascending {
+		trim = pg.redisClient.ZRemRangeByRank(context.TODO(), key+pg.sortedSetKeyTrailing, pg.maxSize, -1)
+	} else {
+		trim = pg.redisClient.ZRemRangeByRank(context.TODO(), key+pg.sortedSetKeyTrailing, 0, -(pg.maxSize + 1))
+	}
+	if trim.Err() != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: trim.Err().Error(),
+			Message: "Failed to trim pagination set on Redis",
+		}
+	}
+
+	if trim.Val() == 0 {
+		return nil
+	}
+
+	deleteSettledKey := pg.redisClient.Del(context.TODO(), key+pg.settledKeyTrailing)
+	if deleteSettledKey.Err() != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: deleteSettledKey.Err().Error(),
+			Message: "Failed to delete settled key on Redis",
+		}
+	}
+
+	// the boundary item was trimmed away
+	if !pg.byCreation() {
+		return pg.settleBoundary(key)
+	}
+
+	return nil
+}
+
+// PastCachedWindow reports whether the page following references can't be
+// served from the sorted set: the set isn't settled and either none of the
+// references is cached anymore or the page runs past its last member.
+func (pg *PaginationType[T]) PastCachedWindow(references []string, paginationParameters ...string) (bool, *types.PaginationError) {
+	key, errorKey := pg.baseKey(paginationParameters)
+	if errorKey != nil {
+		return false, errorKey
+	}
+
+	_, pastWindow, errorWindow := pg.cachedWindow(key, references)
+	return pastWindow, errorWindow
+}
+
+// cachedWindow returns the rank the page following references starts at in
+// the sorted set of key, or -1 when none of references is cached, and
+// whether that page runs past the cached window.
+func (pg *PaginationType[T]) cachedWindow(key string, references []string) (int64, bool, *types.PaginationError) {
+	sortedSetKey := key + pg.sortedSetKeyTrailing
+
+	var settled, totalItem *redis.IntCmd
+	ranks := make([]*redis.IntCmd, len(references))
+	_, errorRead := pg.redisClient.Pipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		settled = pipe.Exists(context.TODO(), key+pg.settledKeyTrailing)
+		totalItem = pipe.ZCard(context.TODO(), sortedSetKey)
+		for i, reference := range references {
+			if pg.direction == ascending {
+				ranks[i] = pipe.ZRank(context.TODO(), sortedSetKey, reference)
+			} else {
+				ranks[i] = pipe.ZRevRank(context.TODO(), sortedSetKey, reference)
+			}
+		}
+		return nil
+	})
+	if errorRead != nil && errorRead != redis.Nil {
+		return 0, false, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorRead.Error(),
+			Message: "Failed to read cached window on Redis",
+		}
+	}
+
+	start := int64(0)
+	if len(references) > 0 {
+		start = -1
+		for i := len(references) - 1; i >= 0; i-- {
+			if ranks[i].Err() == nil {
+				start = ranks[i].Val() + 1
+				break
+			}
+		}
+	}
+
+	if settled.Val() == 1 {
+		return start, false, nil
+	}
+	if start == -1 {
+		return start, true, nil
+	}
+
+	return start, start+pg.itemPerPage > totalItem.Val(), nil
+}
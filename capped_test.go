@@ -0,0 +1,185 @@
+package commoncrud
+
+import (
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+	mock_interfaces "github.com/lefalya/commoncrud/mocks"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCappedSortedSet(t *testing.T) {
+	sortedSetKey := key + descendingTrailing + "createdat"
+
+	newPagination := func(redisDB redis.UniversalClient) *PaginationType[Car] {
+		return Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			2,
+			"",
+			logger,
+			redisDB,
+			nil,
+		).WithMaxSize(3)
+	}
+
+	t.Run("(createdAt descending) trim past max size after adding", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Set(car).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(sortedSetKey).SetVal(3)
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectDel(sortedSetKey + ":settled").SetVal(0)
+		mockRedis.ExpectZAdd(sortedSetKey, redis.Z{
+			Score:  float64(car.GetCreatedAt().UnixMilli()),
+			Member: car.GetRandId(),
+		}).SetVal(1)
+		expectExtendSortedSet(mockRedis, sortedSetKey, sortedSetKey+":settled")
+		mockRedis.ExpectTxPipelineExec()
+		mockRedis.ExpectZRemRangeByRank(sortedSetKey, 0, -4).SetVal(1)
+		mockRedis.ExpectDel(sortedSetKey + ":settled").SetVal(0)
+
+		pagination := newPagination(redisDB)
+		pagination.itemCache = itemCache
+
+		errorAddItem := pagination.AddItem(car, brand, category)
+		assert.Nil(t, errorAddItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	cases := []struct {
+		name       string
+		settled    int64
+		totalItem  int64
+		references []string
+		ranks      []int64
+		expected   bool
+	}{
+		{"settled set serves every page", 1, 3, []string{"a"}, []int64{2}, false},
+		{"first page within the window", 0, 3, nil, nil, false},
+		{"next page within the window", 0, 3, []string{"a"}, []int64{0}, false},
+		{"next page runs past the window", 0, 3, []string{"a", "b"}, []int64{0, 1}, true},
+		{"references trimmed away", 0, 3, []string{"a"}, []int64{-1}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			redisDB, mockRedis := redismock.NewClientMock()
+			mockRedis.ExpectExists(sortedSetKey + ":settled").SetVal(c.settled)
+			mockRedis.ExpectZCard(sortedSetKey).SetVal(c.totalItem)
+			for i, reference := range c.references {
+				if c.ranks[i] < 0 {
+					mockRedis.ExpectZRevRank(sortedSetKey, reference).RedisNil()
+				} else {
+					mockRedis.ExpectZRevRank(sortedSetKey, reference).SetVal(c.ranks[i])
+				}
+			}
+
+			pastWindow, errorWindow := newPagination(redisDB).PastCachedWindow(c.references, brand, category)
+			assert.Nil(t, errorWindow)
+			assert.Equal(t, c.expected, pastWindow)
+			assert.Nil(t, mockRedis.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("fetch next page within the window", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Get(car.GetRandId()).Return(car, nil)
+		itemCache.EXPECT().Get("expired").Return(Car{}, &types.PaginationError{Err: KEY_NOT_FOUND})
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectExists(sortedSetKey + ":settled").SetVal(0)
+		mockRedis.ExpectZCard(sortedSetKey).SetVal(3)
+		mockRedis.ExpectZRevRank(sortedSetKey, "a").SetVal(0)
+		mockRedis.ExpectZRevRange(sortedSetKey, 1, 2).SetVal([]string{car.GetRandId(), "expired"})
+
+		pagination := newPagination(redisDB)
+		pagination.itemCache = itemCache
+
+		items, errorFetch := pagination.FetchLinked([]string{"a"}, nil, nil, brand, category)
+		assert.Nil(t, errorFetch)
+		assert.Equal(t, []Car{car}, items)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("fetch past the window falls back to the seeder", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Get("b").Return(car, nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectExists(sortedSetKey + ":settled").SetVal(0)
+		mockRedis.ExpectZCard(sortedSetKey).SetVal(3)
+		mockRedis.ExpectZRevRank(sortedSetKey, "a").SetVal(0)
+		mockRedis.ExpectZRevRank(sortedSetKey, "b").SetVal(1)
+
+		seeded := NewItem(Car{Brand: brand, Category: category})
+		seeder := func(lastItem Car, limit int64, paginationParameters ...string) ([]Car, *types.PaginationError) {
+			assert.Equal(t, car, lastItem)
+			assert.Equal(t, int64(2), limit)
+			assert.Equal(t, []string{brand, category}, paginationParameters)
+			return []Car{seeded}, nil
+		}
+
+		pagination := newPagination(redisDB)
+		pagination.itemCache = itemCache
+
+		items, errorFetch := pagination.FetchLinked([]string{"a", "b"}, nil, seeder, brand, category)
+		assert.Nil(t, errorFetch)
+		assert.Equal(t, []Car{seeded}, items)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("(uuid ascending) fetch past the window reads the last reference by randId", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		sortableCar := NewSortableItem(Car{Brand: brand, Category: category}, ULID)
+		member := sortableCar.GetUUID() + ":" + sortableCar.GetRandId()
+		sortedSetKey := key + ascendingTrailing + "uuid"
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Get(sortableCar.GetRandId()).Return(sortableCar, nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectExists(sortedSetKey + ":settled").SetVal(0)
+		mockRedis.ExpectZCard(sortedSetKey).SetVal(3)
+		mockRedis.ExpectZRank(sortedSetKey, member).SetVal(2)
+
+		seeder := func(lastItem Car, limit int64, paginationParameters ...string) ([]Car, *types.PaginationError) {
+			assert.Equal(t, sortableCar, lastItem)
+			return nil, nil
+		}
+
+		pagination := Pagination[Car]("car", "uuid", ascending, []string{"brands", "category"}, 2, "", logger, redisDB, itemCache).WithMaxSize(3)
+
+		_, errorFetch := pagination.FetchLinked([]string{member}, nil, seeder, brand, category)
+		assert.Nil(t, errorFetch)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("fetch with references trimmed away and no seeder", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectExists(sortedSetKey + ":settled").SetVal(0)
+		mockRedis.ExpectZCard(sortedSetKey).SetVal(3)
+		mockRedis.ExpectZRevRank(sortedSetKey, "a").RedisNil()
+
+		_, errorFetch := newPagination(redisDB).FetchLinked([]string{"a"}, nil, nil, brand, category)
+		assert.Equal(t, NO_VALID_REFERENCES, errorFetch.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
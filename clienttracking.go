@@ -0,0 +1,163 @@
+package commoncrud
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	TRACKING_MODE_DEFAULT   = "default"
+	TRACKING_MODE_BROADCAST = "broadcast"
+
+	trackingInvalidationChannel = "__redis__:invalidate"
+)
+
+// EnableClientTracking keeps a bounded local copy of items, like
+// WithLocalCache, but lets Redis invalidate it through CLIENT TRACKING rather
+// than our own pub/sub messages.
+//
+// In TRACKING_MODE_DEFAULT Redis remembers every key read through the cache
+// and notifies on change; in TRACKING_MODE_BROADCAST it notifies for every key
+// under the itemKeyFormat prefix. Invalidations are redirected to a dedicated
+// RESP2 connection subscribed to __redis__:invalidate, so options must point
+// at the standalone Redis the cache was created with. Once enabled, Get reads
+// through a tracked client built from options; writes keep using the cache's
+// own client. When the invalidation connection reconnects, the tracked client
+// is rebuilt so its connections redirect to the new one, and the local copy is
+// cleared since invalidations may have been lost meanwhile. ctx bounds the
+// listener; cancel it to stop tracking.
+func (cr *ItemCacheType[T]) EnableClientTracking(
+	ctx context.Context,
+	options *redis.Options,
+	mode string,
+	size int,
+	staleness time.Duration,
+) *types.PaginationError {
+	var redirectId atomic.Int64
+
+	prefix, _, _ := strings.Cut(cr.itemKeyFormat, "%s")
+	trackedOptions := *options
+	onConnect := options.OnConnect
+	trackedOptions.OnConnect = func(ctx context.Context, conn *redis.Conn) error {
+		if onConnect != nil {
+			if errorConnect := onConnect(ctx, conn); errorConnect != nil {
+				return errorConnect
+			}
+		}
+
+		args := []interface{}{"CLIENT", "TRACKING", "ON", "REDIRECT", redirectId.Load()}
+		if mode == TRACKING_MODE_BROADCAST {
+			args = append(args, "BCAST", "PREFIX", prefix)
+		}
+		return conn.Process(ctx, redis.NewCmd(ctx, args...))
+	}
+
+	cr.local = newLocalCache(size, staleness)
+	cr.tracking = true
+
+	invalidationOptions := *options
+	invalidationOptions.Protocol = 2
+	invalidationOptions.PoolSize = 1
+	invalidationOptions.OnConnect = func(ctx context.Context, conn *redis.Conn) error {
+		clientId := conn.ClientID(ctx)
+		if clientId.Err() != nil {
+			return clientId.Err()
+		}
+		if redirectId.Swap(clientId.Val()) != 0 {
+			// tracked connections still redirect to the lost connection
+			cr.retrack(&trackedOptions)
+		}
+		return nil
+	}
+
+	invalidationClient := redis.NewClient(&invalidationOptions)
+	subscriber := invalidationClient.Subscribe(ctx, trackingInvalidationChannel)
+	if _, errorSubscribe := subscriber.Receive(ctx); errorSubscribe != nil {
+		subscriber.Close()
+		invalidationClient.Close()
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorSubscribe.Error(),
+			Message: "Failed to subscribe to tracking invalidation channel",
+		}
+	}
+
+	cr.retrack(&trackedOptions)
+
+	go func() {
+		defer invalidationClient.Close()
+		defer subscriber.Close()
+		defer cr.untrack()
+
+		messages := subscriber.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				cr.invalidateTracked(message)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// reader is the client Get reads from: the tracked client while tracking is
+// on, the cache's own client otherwise.
+func (cr *ItemCacheType[T]) reader() (redis.UniversalClient, bool) {
+	if tracked := cr.trackedClient.Load(); tracked != nil {
+		return tracked, true
+	}
+
+	return cr.redisClient, false
+}
+
+// retrack replaces the tracked client with one built from options, closing
+// the previous one, and clears the local copy it may have missed
+// invalidations for.
+func (cr *ItemCacheType[T]) retrack(options *redis.Options) {
+	previous := cr.trackedClient.Swap(redis.NewClient(options))
+	cr.local.clear()
+	if previous != nil {
+		previous.Close()
+	}
+}
+
+// untrack closes the tracked client once invalidations stop arriving, so Get
+// goes back to the cache's own client and no longer fills the local copy.
+func (cr *ItemCacheType[T]) untrack() {
+	previous := cr.trackedClient.Swap(nil)
+	cr.local.clear()
+	if previous != nil {
+		previous.Close()
+	}
+}
+func (cr *ItemCacheType[T]) invalidateTracked(message *redis.Message) {
+	// a nil payload means the whole database was flushed
+	if len(message.PayloadSlice) == 0 && message.Payload == "" {
+		cr.local.clear()
+		return
+	}
+
+	keys := message.PayloadSlice
+	if len(keys) == 0 {
+		keys = []string{message.Payload}
+	}
+
+	prefix, suffix, _ := strings.Cut(cr.itemKeyFormat, "%s")
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) {
+			continue
+		}
+		cr.local.del(strings.TrimSuffix(strings.TrimPrefix(key, prefix), suffix))
+	}
+}
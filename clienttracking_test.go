@@ -0,0 +1,103 @@
+package commoncrud
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidateTracked(t *testing.T) {
+	t.Run("evicts keys under the item key format", func(t *testing.T) {
+		itemCache := ItemCache[Car](itemKeyFormat, logger, nil)
+		itemCache.local = newLocalCache(10, time.Minute)
+		itemCache.local.set(car.GetRandId(), "{}")
+		itemCache.local.set("otherrandid", "{}")
+
+		itemCache.invalidateTracked(&redis.Message{
+			Channel:      trackingInvalidationChannel,
+			PayloadSlice: []string{"car:" + car.GetRandId(), "aircraft:otherrandid"},
+		})
+
+		_, foundInvalidated := itemCache.local.get(car.GetRandId())
+		_, foundOther := itemCache.local.get("otherrandid")
+		assert.False(t, foundInvalidated)
+		assert.True(t, foundOther)
+	})
+	t.Run("flush clears local cache", func(t *testing.T) {
+		itemCache := ItemCache[Car](itemKeyFormat, logger, nil)
+		itemCache.local = newLocalCache(10, time.Minute)
+		itemCache.local.set(car.GetRandId(), "{}")
+
+		itemCache.invalidateTracked(&redis.Message{Channel: trackingInvalidationChannel})
+
+		_, found := itemCache.local.get(car.GetRandId())
+		assert.False(t, found)
+	})
+}
+
+func TestClientTracking(t *testing.T) {
+	t.Run("get reads through the tracked client and fills the local copy", func(t *testing.T) {
+		encoded, errorMarshal := json.Marshal(car)
+		assert.Nil(t, errorMarshal)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		trackedDB, mockTracked := redismock.NewClientMock()
+		mockTracked.ExpectGet("car:" + car.GetRandId()).SetVal(string(encoded))
+		mockRedis.ExpectExpire("car:"+car.GetRandId(), INDIVIDUAL_KEY_TTL).SetVal(true)
+
+		itemCache := ItemCache[Car](itemKeyFormat, logger, redisDB)
+		itemCache.local = newLocalCache(10, time.Minute)
+		itemCache.tracking = true
+		itemCache.trackedClient.Store(trackedDB)
+
+		item, errorGet := itemCache.Get(car.GetRandId())
+		assert.Nil(t, errorGet)
+		assert.Equal(t, car.GetRandId(), item.GetRandId())
+		_, found := itemCache.local.get(car.GetRandId())
+		assert.True(t, found)
+		assert.Nil(t, mockTracked.ExpectationsWereMet())
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("untracked reads don't fill the local copy", func(t *testing.T) {
+		encoded, errorMarshal := json.Marshal(car)
+		assert.Nil(t, errorMarshal)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet("car:" + car.GetRandId()).SetVal(string(encoded))
+		mockRedis.ExpectExpire("car:"+car.GetRandId(), INDIVIDUAL_KEY_TTL).SetVal(true)
+
+		itemCache := ItemCache[Car](itemKeyFormat, logger, redisDB)
+		itemCache.local = newLocalCache(10, time.Minute)
+		itemCache.tracking = true
+
+		_, errorGet := itemCache.Get(car.GetRandId())
+		assert.Nil(t, errorGet)
+		_, found := itemCache.local.get(car.GetRandId())
+		assert.False(t, found)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("retrack closes the previous client and clears the local copy", func(t *testing.T) {
+		itemCache := ItemCache[Car](itemKeyFormat, logger, nil)
+		itemCache.local = newLocalCache(10, time.Minute)
+		options := &redis.Options{Addr: "localhost:0"}
+
+		itemCache.retrack(options)
+		previous := itemCache.trackedClient.Load()
+		itemCache.local.set(car.GetRandId(), "{}")
+
+		itemCache.retrack(options)
+		assert.NotSame(t, previous, itemCache.trackedClient.Load())
+		assert.Equal(t, redis.ErrClosed, previous.Close())
+		_, found := itemCache.local.get(car.GetRandId())
+		assert.False(t, found)
+
+		current := itemCache.trackedClient.Load()
+		itemCache.untrack()
+		assert.Nil(t, itemCache.trackedClient.Load())
+		assert.Equal(t, redis.ErrClosed, current.Close())
+	})
+}
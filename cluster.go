@@ -0,0 +1,115 @@
+package commoncrud
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const clusterSlots = 16384
+
+// WithHashTag wraps the pagination base key in a {...} hash tag so the
+// sorted set and its bookkeeping keys share one Redis Cluster slot.
+func (pg *PaginationType[T]) WithHashTag() *PaginationType[T] {
+	pg.hashTag = true
+	return pg
+}
+
+// baseKey is the key every pagination set component key is derived from.
+func (pg *PaginationType[T]) baseKey(paginationParameters []string) (string, *types.PaginationError) {
+	key, errorKey := concatKey(pg.paginationRedisFormat, paginationParameters)
+	if errorKey != nil {
+		return "", errorKey
+	}
+	if pg.hashTag {
+		return "{" + key + "}", nil
+	}
+
+	return key, nil
+}
+
+// hashTagOf returns the part of key Redis Cluster hashes: the content of the
+// first non-empty {...}, or the whole key.
+func hashTagOf(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start == -1 {
+		return key
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+
+	return key[start+1 : start+1+end]
+}
+
+// hashSlot maps key to its Redis Cluster slot, CRC16/XMODEM of its hash tag.
+func hashSlot(key string) uint16 {
+	var crc uint16
+	for _, b := range []byte(hashTagOf(key)) {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc % clusterSlots
+}
+
+func sameSlot(keys ...string) bool {
+	for _, key := range keys[1:] {
+		if hashSlot(key) != hashSlot(keys[0]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validateSlot rejects a multi-key operation spanning several slots when the
+// pagination is laid out for Redis Cluster.
+func (pg *PaginationType[T]) validateSlot(keys ...string) *types.PaginationError {
+	if !pg.hashTag || len(keys) < 2 || sameSlot(keys...) {
+		return nil
+	}
+
+	return &types.PaginationError{
+		Err:     CROSS_SLOT_KEYS,
+		Details: strings.Join(keys, ", "),
+		Message: "Multi-key operation spans several cluster slots",
+	}
+}
+
+// writePipelined runs fn in a transaction, unless the pagination is laid out
+// for Redis Cluster and keys, or the event stream fn may write to, span
+// several slots: MULTI can't cross slots, so the writes go out as a plain
+// pipeline instead.
+func (pg *PaginationType[T]) writePipelined(keys []string, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	if pg.eventStream != "" {
+		keys = append(keys, pg.eventStream)
+	}
+	if pg.hashTag && !sameSlot(keys...) {
+		return pg.redisClient.Pipelined(context.TODO(), fn)
+	}
+
+	return pg.redisClient.TxPipelined(context.TODO(), fn)
+}
+
+// writePipelined runs fn in a transaction, or as a plain pipeline once any
+// registered pagination is laid out for Redis Cluster, since every
+// pagination lives on its own slot there.
+func (rg *RegistryType[T]) writePipelined(fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	for _, registered := range rg.paginations {
+		if registered.pagination.hashTag {
+			return rg.redisClient.Pipelined(context.TODO(), fn)
+		}
+	}
+
+	return rg.redisClient.TxPipelined(context.TODO(), fn)
+}
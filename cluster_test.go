@@ -0,0 +1,104 @@
+package commoncrud
+
+import (
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashSlot(t *testing.T) {
+	assert.Equal(t, uint16(12182), hashSlot("foo"))
+	assert.Equal(t, uint16(12739), hashSlot("123456789"))
+	assert.Equal(t, hashSlot("user1000"), hashSlot("{user1000}.following"))
+	assert.Equal(t, hashSlot("{user1000}.following"), hashSlot("{user1000}.followers"))
+	// an empty hash tag hashes the whole key
+	assert.Equal(t, hashSlot("foo{}{bar}"), hashSlot("foo{}{bar}"))
+	assert.NotEqual(t, hashSlot("bar"), hashSlot("foo{}{bar}"))
+}
+
+func TestHashTag(t *testing.T) {
+	t.Run("sorted set and bookkeeping keys share a slot", func(t *testing.T) {
+		sortedSetKey := "{" + key + "}" + ascendingTrailing + "createdat"
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectSet(sortedSetKey+":cardinality", "12", SORTED_SET_TTL).SetVal("OK")
+		mockRedis.ExpectSet(sortedSetKey+":settled", "1", SORTED_SET_TTL).SetVal("OK")
+		mockRedis.ExpectEvalSha(expireTogether.Hash(), []string{
+			sortedSetKey,
+			sortedSetKey + ":settled",
+			sortedSetKey + ":cardinality",
+		}, SORTED_SET_TTL.Milliseconds()).SetVal(int64(3))
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			ascending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		).WithHashTag()
+
+		baseKey, errorKey := pagination.baseKey(paginationParameters)
+		assert.Nil(t, errorKey)
+		assert.True(t, sameSlot(pagination.componentKeys(baseKey)...))
+
+		errorMark := pagination.MarkSeeded(true, 12, brand, category)
+		assert.Nil(t, errorMark)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("query across slots is rejected", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		pagination := Pagination[Car](
+			"car",
+			"ranking",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		).WithHashTag()
+
+		keys := []string{mustSortedSetKey(pagination, brand, "SUV"), mustSortedSetKey(pagination, brand, "Sedan")}
+		if sameSlot(keys...) {
+			t.Skip("keys happen to share a slot")
+		}
+
+		items, errorQuery := pagination.Query(types.SetQuery{
+			Operation: SET_OPERATION_UNION,
+			Keys:      keys,
+		}, nil, nil)
+		assert.Nil(t, items)
+		assert.Equal(t, CROSS_SLOT_KEYS, errorQuery.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("query result shares the slot of its keys", func(t *testing.T) {
+		redisDB, _ := redismock.NewClientMock()
+		pagination := Pagination[Car](
+			"car",
+			"ranking",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		).WithHashTag()
+
+		query := types.SetQuery{
+			Operation: SET_OPERATION_DIFF,
+			Keys:      []string{mustSortedSetKey(pagination, brand, category), "{" + key + "}:excluded"},
+		}
+		assert.Nil(t, pagination.validateSlot(query.Keys...))
+		assert.True(t, sameSlot(append(query.Keys, pagination.queryKey(query))...))
+	})
+}
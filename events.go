@@ -0,0 +1,264 @@
+package commoncrud
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// EVENT_SET and EVENT_DEL are published by ItemCacheType.
+	EVENT_SET = "set"
+	EVENT_DEL = "del"
+	// EVENT_ADD, EVENT_UPDATE and EVENT_REMOVE are published by PaginationType.
+	EVENT_ADD    = "add"
+	EVENT_UPDATE = "update"
+	EVENT_REMOVE = "remove"
+)
+
+// streamEvent is a change event waiting to be XADDed to stream.
+type streamEvent struct {
+	stream string
+	maxLen int64
+	event  types.ChangeEvent
+}
+
+func (se streamEvent) add(pipe redis.Pipeliner) {
+	pipe.XAdd(context.TODO(), &redis.XAddArgs{
+		Stream: se.stream,
+		MaxLen: se.maxLen,
+		Approx: se.maxLen > 0,
+		Values: []interface{}{
+			"operation", se.event.Operation,
+			"entity", se.event.Entity,
+			"randid", se.event.RandId,
+			"key", se.event.PaginationKey,
+			"version", se.event.Version,
+		},
+	})
+}
+
+// writeWithEvents queues the writes of fn followed by events. With events,
+// or when transaction is set, they run as one MULTI/EXEC, except on Redis
+// Cluster where the stream lives on another slot and a plain pipeline is
+// used instead.
+func writeWithEvents(redisClient redis.UniversalClient, transaction bool, events []streamEvent, fn func(redis.Pipeliner)) error {
+	write := func(pipe redis.Pipeliner) error {
+		fn(pipe)
+		for _, event := range events {
+			event.add(pipe)
+		}
+		return nil
+	}
+
+	_, cluster := redisClient.(*redis.ClusterClient)
+	if cluster || (!transaction && len(events) == 0) {
+		_, errorExec := redisClient.Pipelined(context.TODO(), write)
+		return errorExec
+	}
+
+	_, errorExec := redisClient.TxPipelined(context.TODO(), write)
+	return errorExec
+}
+
+// WithEvents publishes a "set" or "del" event for entityName to stream on
+// every write, in the transaction of the write itself. maxLen caps the
+// stream approximately, 0 leaves it unbounded.
+func (cr *ItemCacheType[T]) WithEvents(stream string, entityName string, maxLen int64) *ItemCacheType[T] {
+	cr.eventStream = stream
+	cr.entityName = entityName
+	cr.eventMaxLen = maxLen
+	return cr
+}
+
+// events returns the event of operation on item, when the cache
+// publishes events.
+func (cr *ItemCacheType[T]) events(operation string, item T) []streamEvent {
+	if cr.eventStream == "" {
+		return nil
+	}
+
+	return []streamEvent{{
+		stream: cr.eventStream,
+		maxLen: cr.eventMaxLen,
+		event: types.ChangeEvent{
+			Operation: operation,
+			Entity:    cr.entityName,
+			RandId:    item.GetRandId(),
+			Version:   item.GetUpdatedAt().UnixMilli(),
+		},
+	}}
+}
+
+type EventConsumerType struct {
+	redisClient redis.UniversalClient
+	stream      string
+	group       string
+	consumer    string
+	count       int64
+	block       time.Duration
+}
+
+// EventConsumer reads the change events of stream as consumer within the
+// consumer group group. Events stay pending in the group until acknowledged.
+func EventConsumer(redisClient redis.UniversalClient, stream string, group string, consumer string) *EventConsumerType {
+	return &EventConsumerType{
+		redisClient: redisClient,
+		stream:      stream,
+		group:       group,
+		consumer:    consumer,
+		count:       EVENT_BATCH_SIZE,
+		block:       EVENT_BLOCK,
+	}
+}
+
+// WithBatch overrides how many events a read returns at most and how long
+// it waits for new ones.
+func (ec *EventConsumerType) WithBatch(count int64, block time.Duration) *EventConsumerType {
+	ec.count = count
+	ec.block = block
+	return ec
+}
+
+// EnsureGroup creates the consumer group, and the stream if needed, reading
+// from the start of the stream. An existing group is left untouched.
+func (ec *EventConsumerType) EnsureGroup() *types.PaginationError {
+	create := ec.redisClient.XGroupCreateMkStream(context.TODO(), ec.stream, ec.group, "0")
+	if create.Err() != nil && !strings.HasPrefix(create.Err().Error(), "BUSYGROUP") {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: create.Err().Error(),
+			Message: "Failed to create consumer group on Redis",
+		}
+	}
+
+	return nil
+}
+
+// Read returns the next events never delivered to the group, waiting up to
+// the block duration for some.
+func (ec *EventConsumerType) Read() ([]types.ChangeEvent, *types.PaginationError) {
+	return ec.read(">", ec.block)
+}
+
+// Pending returns the events delivered to this consumer but not acknowledged
+// yet, starting after the entry ID after ("0" for the first).
+func (ec *EventConsumerType) Pending(after string) ([]types.ChangeEvent, *types.PaginationError) {
+	// a negative block leaves BLOCK out, history reads never wait
+	return ec.read(after, -1)
+}
+
+// Ack acknowledges handled events so they leave the pending list.
+func (ec *EventConsumerType) Ack(ids ...string) *types.PaginationError {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	ack := ec.redisClient.XAck(context.TODO(), ec.stream, ec.group, ids...)
+	if ack.Err() != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: ack.Err().Error(),
+			Message: "Failed to acknowledge events on Redis",
+		}
+	}
+
+	return nil
+}
+
+// Run hands events to handler until ctx is cancelled, first replaying those
+// left pending by a previous run. Events are acknowledged once handler
+// returns nil; failed ones stay pending for the next run.
+func (ec *EventConsumerType) Run(ctx context.Context, handler func(event types.ChangeEvent) error) *types.PaginationError {
+	after := "0"
+	for {
+		events, errorRead := ec.Pending(after)
+		if errorRead != nil {
+			return errorRead
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		errorHandle := ec.handle(events, handler)
+		if errorHandle != nil {
+			return errorHandle
+		}
+		after = events[len(events)-1].ID
+	}
+
+	for ctx.Err() == nil {
+		events, errorRead := ec.Read()
+		if errorRead != nil {
+			return errorRead
+		}
+
+		errorHandle := ec.handle(events, handler)
+		if errorHandle != nil {
+			return errorHandle
+		}
+	}
+
+	return nil
+}
+
+func (ec *EventConsumerType) handle(events []types.ChangeEvent, handler func(event types.ChangeEvent) error) *types.PaginationError {
+	var handled []string
+	for _, event := range events {
+		if handler(event) == nil {
+			handled = append(handled, event.ID)
+		}
+	}
+
+	return ec.Ack(handled...)
+}
+
+func (ec *EventConsumerType) read(id string, block time.Duration) ([]types.ChangeEvent, *types.PaginationError) {
+	streams := ec.redisClient.XReadGroup(context.TODO(), &redis.XReadGroupArgs{
+		Group:    ec.group,
+		Consumer: ec.consumer,
+		Streams:  []string{ec.stream, id},
+		Count:    ec.count,
+		Block:    block,
+	})
+	if streams.Err() != nil {
+		if streams.Err() == redis.Nil {
+			return nil, nil
+		}
+		return nil, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: streams.Err().Error(),
+			Message: "Failed to read change events on Redis",
+		}
+	}
+
+	var events []types.ChangeEvent
+	for _, stream := range streams.Val() {
+		for _, message := range stream.Messages {
+			events = append(events, parseEvent(message))
+		}
+	}
+
+	return events, nil
+}
+
+func parseEvent(message redis.XMessage) types.ChangeEvent {
+	field := func(name string) string {
+		value, _ := message.Values[name].(string)
+		return value
+	}
+	version, _ := strconv.ParseInt(field("version"), 10, 64)
+
+	return types.ChangeEvent{
+		ID:            message.ID,
+		Operation:     field("operation"),
+		Entity:        field("entity"),
+		RandId:        field("randid"),
+		PaginationKey: field("key"),
+		Version:       version,
+	}
+}
@@ -0,0 +1,260 @@
+package commoncrud
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+	mock_interfaces "github.com/lefalya/commoncrud/mocks"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeEvents(t *testing.T) {
+	stream := "car:events"
+	sortedSetKey := key + descendingTrailing + "createdat"
+
+	xadd := func(operation string, paginationKey string) *redis.XAddArgs {
+		return &redis.XAddArgs{
+			Stream: stream,
+			MaxLen: 1000,
+			Approx: true,
+			Values: []interface{}{
+				"operation", operation,
+				"entity", "car",
+				"randid", car.GetRandId(),
+				"key", paginationKey,
+				"version", car.GetUpdatedAt().UnixMilli(),
+			},
+		}
+	}
+
+	newPagination := func(redisDB redis.UniversalClient) *PaginationType[Car] {
+		return Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		).WithEvents(stream, 1000)
+	}
+
+	t.Run("item write and its event share a transaction", func(t *testing.T) {
+		carInByte, errorEncode := ItemCache[Car](itemKeyFormat, logger, nil).encode(car)
+		assert.Nil(t, errorEncode)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectSet("car:"+car.GetRandId(), string(carInByte), INDIVIDUAL_KEY_TTL).SetVal("OK")
+		mockRedis.ExpectXAdd(xadd(EVENT_SET, "")).SetVal("1-0")
+		mockRedis.ExpectTxPipelineExec()
+
+		itemCache := ItemCache[Car](itemKeyFormat, logger, redisDB).WithEvents(stream, "car", 1000)
+		assert.Nil(t, itemCache.Set(car))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("remove event joins the sorted set removal", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectDel("car:" + car.GetRandId()).SetVal(1)
+		expectMembership(mockRedis, sortedSetKey, car.GetRandId(), 0, descending, redis.Z{Score: 1, Member: "oldest"})
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZRem(sortedSetKey, car.GetRandId()).SetVal(1)
+		mockRedis.ExpectXAdd(xadd(EVENT_REMOVE, sortedSetKey)).SetVal("1-0")
+		mockRedis.ExpectTxPipelineExec()
+
+		assert.Nil(t, newPagination(redisDB).RemoveItem(car, brand, category))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("no remove event for an item outside the sorted set", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectDel("car:" + car.GetRandId()).SetVal(1)
+		expectMembership(mockRedis, sortedSetKey, car.GetRandId(), -1, descending)
+
+		assert.Nil(t, newPagination(redisDB).RemoveItem(car, brand, category))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("no remove event moving an item outside the previous sorted set", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		previousKey := mustConcatKey(paginationKeyFormat, []string{brand, "Sedan"}) + descendingTrailing + "createdat"
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Set(car).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(sortedSetKey).SetVal(3)
+		expectMembership(mockRedis, previousKey, car.GetRandId(), -1, descending, redis.Z{Score: 1, Member: "oldest"})
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZAdd(sortedSetKey, redis.Z{
+			Score:  float64(car.GetCreatedAt().UnixMilli()),
+			Member: car.GetRandId(),
+		}).SetVal(1)
+		expectExtendSortedSet(mockRedis, sortedSetKey, sortedSetKey+":settled")
+		mockRedis.ExpectXAdd(xadd(EVENT_ADD, sortedSetKey)).SetVal("1-0")
+		mockRedis.ExpectTxPipelineExec()
+
+		pagination := newPagination(redisDB)
+		pagination.itemCache = itemCache
+
+		assert.Nil(t, pagination.MoveItem(car, []string{brand, "Sedan"}, brand, category))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("add event joins the sorted set write", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Set(car).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(sortedSetKey).SetVal(3)
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZAdd(sortedSetKey, redis.Z{
+			Score:  float64(car.GetCreatedAt().UnixMilli()),
+			Member: car.GetRandId(),
+		}).SetVal(1)
+		expectExtendSortedSet(mockRedis, sortedSetKey, sortedSetKey+":settled")
+		mockRedis.ExpectXAdd(xadd(EVENT_ADD, sortedSetKey)).SetVal("1-0")
+		mockRedis.ExpectTxPipelineExec()
+
+		pagination := newPagination(redisDB)
+		pagination.itemCache = itemCache
+
+		assert.Nil(t, pagination.AddItem(car, brand, category))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("no add event while the sorted set isn't cached", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(sortedSetKey).SetVal(0)
+
+		pagination := newPagination(redisDB)
+		pagination.itemCache = itemCache
+
+		assert.Nil(t, pagination.AddItem(car, brand, category))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("update event follows the item in the sorted set", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Set(car).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZRank(sortedSetKey, car.GetRandId()).SetVal(1)
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectXAdd(xadd(EVENT_UPDATE, sortedSetKey)).SetVal("1-0")
+		mockRedis.ExpectTxPipelineExec()
+
+		pagination := newPagination(redisDB)
+		pagination.itemCache = itemCache
+
+		assert.Nil(t, pagination.UpdateItem(car, brand, category))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
+
+func TestEventConsumer(t *testing.T) {
+	stream := "car:events"
+	message := redis.XMessage{
+		ID: "1700000000000-0",
+		Values: map[string]interface{}{
+			"operation": EVENT_ADD,
+			"entity":    "car",
+			"randid":    "abc",
+			"key":       "car:brands:volkswagen:descby:createdat",
+			"version":   "1700000000000",
+		},
+	}
+	expected := types.ChangeEvent{
+		ID:            "1700000000000-0",
+		Operation:     EVENT_ADD,
+		Entity:        "car",
+		RandId:        "abc",
+		PaginationKey: "car:brands:volkswagen:descby:createdat",
+		Version:       1700000000000,
+	}
+
+	readArgs := func(id string, block time.Duration) *redis.XReadGroupArgs {
+		return &redis.XReadGroupArgs{
+			Group:    "indexer",
+			Consumer: "indexer-1",
+			Streams:  []string{stream, id},
+			Count:    EVENT_BATCH_SIZE,
+			Block:    block,
+		}
+	}
+
+	t.Run("keep an existing group", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectXGroupCreateMkStream(stream, "indexer", "0").
+			SetErr(errors.New("BUSYGROUP Consumer Group name already exists"))
+
+		errorGroup := EventConsumer(redisDB, stream, "indexer", "indexer-1").EnsureGroup()
+		assert.Nil(t, errorGroup)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("read typed events", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectXReadGroup(readArgs(">", EVENT_BLOCK)).SetVal([]redis.XStream{
+			{Stream: stream, Messages: []redis.XMessage{message}},
+		})
+
+		events, errorRead := EventConsumer(redisDB, stream, "indexer", "indexer-1").Read()
+		assert.Nil(t, errorRead)
+		assert.Equal(t, []types.ChangeEvent{expected}, events)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("run replays pending events and acknowledges handled ones", func(t *testing.T) {
+		failed := redis.XMessage{ID: "1699999999999-0", Values: map[string]interface{}{"operation": EVENT_DEL}}
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectXReadGroup(readArgs("0", -1)).SetVal([]redis.XStream{
+			{Stream: stream, Messages: []redis.XMessage{failed}},
+		})
+		mockRedis.ExpectXReadGroup(readArgs(failed.ID, -1)).SetVal([]redis.XStream{
+			{Stream: stream},
+		})
+		mockRedis.ExpectXReadGroup(readArgs(">", EVENT_BLOCK)).SetVal([]redis.XStream{
+			{Stream: stream, Messages: []redis.XMessage{message}},
+		})
+		mockRedis.ExpectXAck(stream, "indexer", message.ID).SetVal(1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var handled []types.ChangeEvent
+		errorRun := EventConsumer(redisDB, stream, "indexer", "indexer-1").Run(ctx, func(event types.ChangeEvent) error {
+			handled = append(handled, event)
+			if event.Operation == EVENT_DEL {
+				return errors.New("index unavailable")
+			}
+			cancel()
+			return nil
+		})
+		assert.Nil(t, errorRun)
+		assert.Equal(t, expected, handled[1])
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,172 @@
+package commoncrud
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/lefalya/commoncrud/interfaces"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const facetKeyInfix = ":facet:"
+
+// incrementFacet adjusts a facet count without recreating an expired hash,
+// removing values whose count drops to zero.
+var incrementFacet = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return false
+end
+local count = redis.call("HINCRBY", KEYS[1], ARGV[1], ARGV[2])
+if count <= 0 then
+	redis.call("HDEL", KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// WithFacets maintains per filter value counts on AddItem, RemoveItem and
+// MoveItem. seeder rebuilds the counts when Facets finds none cached.
+func (pg *PaginationType[T]) WithFacets(seeder interfaces.FacetSeedFunc) *PaginationType[T] {
+	pg.facetSeeder = seeder
+	return pg
+}
+
+// facetKey is the hash counting items per value of the filter at
+// filterIndex, narrowed by the values of the other filters.
+func (pg *PaginationType[T]) facetKey(filterIndex int, otherParameters []string) (string, *types.PaginationError) {
+	keyFormat := namespacedFormat(pg.namespace, pg.entityName)
+	for i, filter := range pg.filter {
+		if i != filterIndex {
+			keyFormat += ":" + filter + ":%s"
+		}
+	}
+
+	key, errorKey := concatKey(keyFormat, otherParameters)
+	if errorKey != nil {
+		return "", errorKey
+	}
+
+	return key + pg.sortedSetKeyTrailing + facetKeyInfix + pg.filter[filterIndex], nil
+}
+
+// countFacets adds delta to the count of each filter value of an item listed
+// under paginationParameters. Expired facets are left to be reseeded.
+func (pg *PaginationType[T]) countFacets(paginationParameters []string, delta int64) *types.PaginationError {
+	if pg.facetSeeder == nil || len(paginationParameters) != len(pg.filter) {
+		return nil
+	}
+
+	for i := range pg.filter {
+		otherParameters := make([]string, 0, len(paginationParameters)-1)
+		otherParameters = append(otherParameters, paginationParameters[:i]...)
+		otherParameters = append(otherParameters, paginationParameters[i+1:]...)
+
+		key, errorKey := pg.facetKey(i, otherParameters)
+		if errorKey != nil {
+			return errorKey
+		}
+
+		increment := incrementFacet.Run(
+			context.TODO(),
+			pg.redisClient,
+			[]string{key},
+			paginationParameters[i],
+			delta,
+		)
+		if increment.Err() != nil && increment.Err() != redis.Nil {
+			return &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: increment.Err().Error(),
+				Message: "Failed to count facet on Redis",
+			}
+		}
+	}
+
+	return nil
+}
+
+// Facets returns the number of items per value of filterName, within the
+// list narrowed by paginationParameters: the values of the other filters, in
+// order. Counts missing from Redis are rebuilt from the facet seeder.
+func (pg *PaginationType[T]) Facets(filterName string, paginationParameters ...string) (map[string]int64, *types.PaginationError) {
+	filterIndex := -1
+	for i, filter := range pg.filter {
+		if filter == filterName {
+			filterIndex = i
+			break
+		}
+	}
+	if filterIndex == -1 {
+		return nil, &types.PaginationError{
+			Err:     UNKNOWN_FILTER,
+			Details: filterName,
+			Message: "Facet requested for a filter the pagination doesn't have",
+		}
+	}
+
+	key, errorKey := pg.facetKey(filterIndex, paginationParameters)
+	if errorKey != nil {
+		return nil, errorKey
+	}
+
+	cached := pg.redisClient.HGetAll(context.TODO(), key)
+	if cached.Err() != nil {
+		return nil, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: cached.Err().Error(),
+			Message: "Failed to get facets from Redis",
+		}
+	}
+
+	facets := make(map[string]int64, len(cached.Val()))
+	if len(cached.Val()) > 0 {
+		for value, count := range cached.Val() {
+			parsed, errorParseInt := strconv.ParseInt(count, 10, 64)
+			if errorParseInt != nil {
+				return nil, &types.PaginationError{
+					Err:     REDIS_FATAL_ERROR,
+					Details: errorParseInt.Error(),
+					Message: "Failed to parse facet count",
+				}
+			}
+			facets[value] = parsed
+		}
+
+		return facets, nil
+	}
+
+	if pg.facetSeeder == nil {
+		return facets, nil
+	}
+
+	seeded, errorSeed := pg.facetSeeder(filterName, paginationParameters...)
+	if errorSeed != nil {
+		return nil, errorSeed
+	}
+
+	values := make(map[string]interface{}, len(seeded))
+	for value, count := range seeded {
+		if count > 0 {
+			values[value] = count
+			facets[value] = count
+		}
+	}
+	if len(values) == 0 {
+		return facets, nil
+	}
+
+	_, errorPipeline := pg.redisClient.TxPipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		pipe.HSet(context.TODO(), key, values)
+		pipe.PExpire(context.TODO(), key, SORTED_SET_TTL)
+		return nil
+	})
+	if errorPipeline != nil {
+		return nil, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorPipeline.Error(),
+			Message: "Failed to set facets on Redis",
+		}
+	}
+
+	return facets, nil
+}
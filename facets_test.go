@@ -0,0 +1,155 @@
+package commoncrud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+	mock_interfaces "github.com/lefalya/commoncrud/mocks"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFacets(t *testing.T) {
+	categoryFacetKey := "car:brands:volkswagen:descby:createdat:facet:category"
+	brandFacetKey := "car:category:suv:descby:createdat:facet:brands"
+
+	newPagination := func(redisDB redis.UniversalClient, seeder func(string, ...string) (map[string]int64, *types.PaginationError)) *PaginationType[Car] {
+		return Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		).WithFacets(seeder)
+	}
+
+	t.Run("return cached counts", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectHGetAll(categoryFacetKey).SetVal(map[string]string{
+			"SUV":   "120",
+			"Sedan": "80",
+		})
+
+		pagination := newPagination(redisDB, func(string, ...string) (map[string]int64, *types.PaginationError) {
+			t.Fatal("seeder must not be called when counts are cached")
+			return nil, nil
+		})
+
+		facets, errorFacets := pagination.Facets("category", brand)
+		assert.Nil(t, errorFacets)
+		assert.Equal(t, map[string]int64{"SUV": 120, "Sedan": 80}, facets)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("rebuild missing counts from the seeder", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectHGetAll(categoryFacetKey).SetVal(map[string]string{})
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectHSet(categoryFacetKey, map[string]interface{}{"SUV": int64(120)}).SetVal(1)
+		mockRedis.ExpectPExpire(categoryFacetKey, SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectTxPipelineExec()
+
+		pagination := newPagination(redisDB, func(filterName string, paginationParameters ...string) (map[string]int64, *types.PaginationError) {
+			assert.Equal(t, "category", filterName)
+			assert.Equal(t, []string{brand}, paginationParameters)
+			return map[string]int64{"SUV": 120, "Coupe": 0}, nil
+		})
+
+		facets, errorFacets := pagination.Facets("category", brand)
+		assert.Nil(t, errorFacets)
+		assert.Equal(t, map[string]int64{"SUV": 120}, facets)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("unknown filter", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		pagination := newPagination(redisDB, nil)
+
+		facets, errorFacets := pagination.Facets("color", brand)
+		assert.Nil(t, facets)
+		assert.Equal(t, UNKNOWN_FILTER, errorFacets.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("add and remove item count every filter value", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Del(car).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(key + ":descby:createdat").SetVal(0)
+		mockRedis.ExpectEvalSha(incrementFacet.Hash(), []string{brandFacetKey}, brand, int64(1)).SetVal(int64(121))
+		mockRedis.ExpectEvalSha(incrementFacet.Hash(), []string{categoryFacetKey}, category, int64(1)).RedisNil()
+
+		expectMembership(mockRedis, key+":descby:createdat", car.GetRandId(), -1, descending)
+		mockRedis.ExpectEvalSha(incrementFacet.Hash(), []string{brandFacetKey}, brand, int64(-1)).SetVal(int64(120))
+		mockRedis.ExpectEvalSha(incrementFacet.Hash(), []string{categoryFacetKey}, category, int64(-1)).RedisNil()
+
+		pagination := newPagination(redisDB, func(string, ...string) (map[string]int64, *types.PaginationError) {
+			return nil, nil
+		})
+		pagination.itemCache = itemCache
+
+		assert.Nil(t, pagination.AddItem(car, brand, category))
+		assert.Nil(t, pagination.RemoveItem(car, brand, category))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("adding an item already listed isn't counted again", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Set(car).Return(nil)
+
+		sortedSetKey := key + ":descby:createdat"
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(sortedSetKey).SetVal(3)
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZAdd(sortedSetKey, redis.Z{
+			Score:  float64(car.GetCreatedAt().UnixMilli()),
+			Member: car.GetRandId(),
+		}).SetVal(0)
+		expectExtendSortedSet(mockRedis, sortedSetKey, sortedSetKey+":settled")
+		mockRedis.ExpectTxPipelineExec()
+
+		pagination := newPagination(redisDB, func(string, ...string) (map[string]int64, *types.PaginationError) {
+			return nil, nil
+		})
+		pagination.itemCache = itemCache
+
+		assert.Nil(t, pagination.AddItem(car, brand, category))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("removing an item gone from the cached window isn't counted again", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Del(car).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		expectMembership(mockRedis, key+":descby:createdat", car.GetRandId(), -1, descending, redis.Z{
+			Score:  float64(car.GetCreatedAt().Add(-time.Hour).UnixMilli()),
+			Member: "oldest",
+		})
+
+		pagination := newPagination(redisDB, func(string, ...string) (map[string]int64, *types.PaginationError) {
+			return nil, nil
+		})
+		pagination.itemCache = itemCache
+
+		assert.Nil(t, pagination.RemoveItem(car, brand, category))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
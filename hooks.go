@@ -0,0 +1,44 @@
+package commoncrud
+
+import (
+	"github.com/lefalya/commoncrud/interfaces"
+	"github.com/lefalya/commoncrud/types"
+)
+
+// hook returns the H implemented by item, looking at *item too so hooks
+// with pointer receivers are found on value items.
+func hook[H any, T interfaces.Item](item *T) (H, bool) {
+	if implemented, ok := any(*item).(H); ok {
+		return implemented, true
+	}
+	implemented, ok := any(item).(H)
+	return implemented, ok
+}
+
+// beforeWrite runs the BeforeSet and Validate hooks item implements, in that
+// order, so validation sees the normalised item.
+func beforeWrite[T interfaces.Item](item *T) *types.PaginationError {
+	if setter, ok := hook[interfaces.BeforeSetter](item); ok {
+		setter.BeforeSet()
+	}
+
+	if validator, ok := hook[interfaces.Validator](item); ok {
+		errorValidate := validator.Validate()
+		if errorValidate != nil {
+			return &types.PaginationError{
+				Err:     ITEM_INVALID,
+				Details: errorValidate.Error(),
+				Message: "Refused to write an invalid item",
+			}
+		}
+	}
+
+	return nil
+}
+
+// afterRead runs the AfterGet hook item implements.
+func afterRead[T interfaces.Item](item *T) {
+	if getter, ok := hook[interfaces.AfterGetter](item); ok {
+		getter.AfterGet()
+	}
+}
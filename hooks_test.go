@@ -0,0 +1,127 @@
+package commoncrud
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+	mock_interfaces "github.com/lefalya/commoncrud/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type Student struct {
+	*Item
+	Name string `bson:"name"`
+	Slug string `json:"-" bson:"-"`
+}
+
+func (s *Student) BeforeSet() {
+	s.Name = strings.TrimSpace(s.Name)
+}
+
+func (s *Student) Validate() error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func (s *Student) AfterGet() {
+	s.Slug = strings.ToLower(strings.ReplaceAll(s.Name, " ", "-"))
+}
+
+func TestHooks(t *testing.T) {
+	studentKeyFormat := "student:%s"
+
+	t.Run("set normalises the item before writing it", func(t *testing.T) {
+		student := NewItem(&Student{Name: "  Ada Lovelace "})
+		normalised, errorMarshal := json.Marshal(&Student{Item: student.Item, Name: "Ada Lovelace"})
+		assert.Nil(t, errorMarshal)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectSet("student:"+student.GetRandId(), string(normalised), INDIVIDUAL_KEY_TTL).SetVal("OK")
+
+		errorSet := ItemCache[*Student](studentKeyFormat, logger, redisDB).Set(student)
+		assert.Nil(t, errorSet)
+		assert.Equal(t, "Ada Lovelace", student.Name)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("set refuses an invalid item", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+
+		errorSet := ItemCache[*Student](studentKeyFormat, logger, redisDB).Set(NewItem(&Student{Name: " "}))
+		assert.Equal(t, ITEM_INVALID, errorSet.Err)
+		assert.Equal(t, "name is required", errorSet.Details)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("get completes the item after reading it", func(t *testing.T) {
+		student := NewItem(&Student{Name: "Ada Lovelace"})
+		studentInByte, errorMarshal := json.Marshal(student)
+		assert.Nil(t, errorMarshal)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet("student:" + student.GetRandId()).SetVal(string(studentInByte))
+		mockRedis.ExpectExpire("student:"+student.GetRandId(), INDIVIDUAL_KEY_TTL).SetVal(true)
+
+		item, errorGet := ItemCache[*Student](studentKeyFormat, logger, redisDB).Get(student.GetRandId())
+		assert.Nil(t, errorGet)
+		assert.Equal(t, "ada-lovelace", item.Slug)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("pagination validates ahead of an injected item cache", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		pagination := Pagination[*Student](
+			"student",
+			"createdat",
+			descending,
+			[]string{"name"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			mock_interfaces.NewMockItemCache[*Student](ctrl),
+		)
+
+		invalid := NewItem(&Student{})
+		errorAddItem := pagination.AddItem(invalid, "ada")
+		assert.Equal(t, ITEM_INVALID, errorAddItem.Err)
+		errorUpdateItem := pagination.UpdateItem(invalid, "ada")
+		assert.Equal(t, ITEM_INVALID, errorUpdateItem.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("pointer receiver hooks run on value items", func(t *testing.T) {
+		student := NewItem(Student{Name: "  Ada Lovelace "})
+		normalised, errorMarshal := json.Marshal(Student{Item: student.Item, Name: "Ada Lovelace"})
+		assert.Nil(t, errorMarshal)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectSet("student:"+student.GetRandId(), string(normalised), INDIVIDUAL_KEY_TTL).SetVal("OK")
+
+		itemCache := ItemCache[Student](studentKeyFormat, logger, redisDB)
+		assert.Nil(t, itemCache.Set(student))
+		assert.Equal(t, ITEM_INVALID, itemCache.Set(NewItem(Student{})).Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("registry validates ahead of an injected item cache", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		registry := Registry[*Student](mock_interfaces.NewMockItemCache[*Student](ctrl), logger, redisDB)
+
+		invalid := NewItem(&Student{})
+		assert.Equal(t, ITEM_INVALID, registry.Add(invalid).Err)
+		assert.Equal(t, ITEM_INVALID, registry.Update(invalid).Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
@@ -1,8 +1,10 @@
 package interfaces
 
 import (
-	"github.com/lefalya/commoncrud/types"
+	"context"
 	"time"
+
+	"github.com/lefalya/commoncrud/types"
 )
 
 type Item interface {
@@ -14,10 +16,32 @@ type Item interface {
 	GetCreatedAt() time.Time
 	SetUpdatedAt(time time.Time)
 	GetUpdatedAt() time.Time
-	SetCreatedAtString(timeString string)
-	GetCreatedAtString() string
-	SetUpdatedAtString(timeString string)
-	GetUpdatedAtString() string
+}
+
+// Deletable is implemented by items tracking soft deletion, like those
+// embedding commoncrud.Item. A zero DeletedAt means the item is live.
+type Deletable interface {
+	SetDeletedAt(time time.Time)
+	GetDeletedAt() time.Time
+}
+
+// Validator is implemented by items checked before every write. A non-nil
+// error aborts the write with ITEM_INVALID.
+type Validator interface {
+	Validate() error
+}
+
+// BeforeSetter is implemented by items normalised before they are validated
+// and written. It may run once per layer the item passes through, so it must
+// be idempotent.
+type BeforeSetter interface {
+	BeforeSet()
+}
+
+// AfterGetter is implemented by items completing derived fields once read
+// back from the cache.
+type AfterGetter interface {
+	AfterGet()
 }
 
 // individual key format: individualKeyFormat:[item.RandId]
@@ -31,24 +55,84 @@ type Pagination[T Item] interface {
 	FetchLinked(
 		references []string,
 		processor PaginationProcessor[T],
+		seeder LinkedSeedFunc[T],
 		paginationParameters ...string,
 	) ([]T, *types.PaginationError)
 	FetchAll(processor PaginationProcessor[T], paginationParameters ...string) ([]T, *types.PaginationError)
 	SeedOne(randId string) (*T, *types.PaginationError)
 	SeedLinked(
 		lastItem T,
-		processor SeedProcessor[T],
+		seeder LinkedSeedFunc[T],
 		paginationParameters ...string,
 	) ([]T, *types.PaginationError)
 	SeedAll(processor SeedProcessor[T], paginationParameters ...string) ([]T, *types.PaginationError)
 	SeedCardinality(paginationParameters ...string) *types.PaginationError
 }
 
+// IdGenerator returns a new randId. Like uuid.New, it panics when no
+// randomness is available.
+type IdGenerator func() string
+
 type PaginationProcessor[T Item] func(item T, items *[]T)
 type SeedProcessor[T Item] func(item *T)
 
+// SeedFunc reseeds a pagination set from the database. fencingToken is the
+// token of the seed lock held by the caller, or 0 when no lock is configured.
+// Loaded items go through live before being written, so soft deleted ones
+// aren't cached again.
+type SeedFunc[T Item] func(fencingToken int64, live ItemFilter[T]) ([]T, *types.PaginationError)
+
+// ItemFilter returns the items to keep out of items, in order.
+type ItemFilter[T Item] func(items []T) ([]T, *types.PaginationError)
+
+// LinkedSeedFunc loads from the database the page of at most limit items
+// following lastItem in pagination order, or the first page when lastItem is
+// the zero T.
+type LinkedSeedFunc[T Item] func(lastItem T, limit int64, paginationParameters ...string) ([]T, *types.PaginationError)
+
+// FacetSeedFunc counts items per value of filterName from the database,
+// within the list narrowed by the values of the other filters.
+type FacetSeedFunc func(filterName string, paginationParameters ...string) (map[string]int64, *types.PaginationError)
+
+// ItemLoader loads an item from the database on a cache miss. It must return
+// an error wrapping commoncrud's KEY_NOT_FOUND when the item does not exist.
+type ItemLoader[T Item] func(ctx context.Context, randId string) (T, error)
+
 type ItemCache[T Item] interface {
 	Get(randId string) (T, *types.PaginationError)
 	Set(item T) *types.PaginationError
 	Del(item T) *types.PaginationError
 }
+
+// SoftDeleter is implemented by item caches keeping a tombstone of the items
+// they soft delete, see commoncrud.PaginationType.WithSoftDelete.
+type SoftDeleter[T Item] interface {
+	// SoftDel removes item but keeps a tombstone it can be restored from.
+	SoftDel(item T) *types.PaginationError
+	Restore(randId string) (T, *types.PaginationError)
+	// Tombstoned reports, for each of randIds, whether it was soft deleted.
+	Tombstoned(randIds ...string) ([]bool, *types.PaginationError)
+}
+
+// Persister writes a batch of items flushed by the write-behind worker back
+// to the database, and deletes the items removed from the cache.
+type Persister[T Item] interface {
+	Persist(items []T) error
+	Delete(randIds []string) error
+}
+
+// ChangeStream tails the changes of the source of truth, like a MongoDB
+// change stream, see commoncrud.Syncer.
+type ChangeStream[T Item] interface {
+	// Next waits for the next change. It returns false once ctx is done or
+	// the stream failed, see Err.
+	Next(ctx context.Context) bool
+	// Current decodes the change Next moved to.
+	Current() (types.SourceChange[T], error)
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// ChangeStreamOpener opens a change stream resuming after resumeToken, or
+// starting now when resumeToken is nil.
+type ChangeStreamOpener[T Item] func(ctx context.Context, resumeToken []byte) (ChangeStream[T], error)
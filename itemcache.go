@@ -3,8 +3,10 @@ package commoncrud
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"github.com/lefalya/commoncrud/interfaces"
@@ -12,10 +14,27 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	negativeKeyTrailing  = ":notfound"
+	tombstoneKeyTrailing = ":tombstone"
+)
+
 type ItemCacheType[T interfaces.Item] struct {
 	itemKeyFormat string
 	logger        *slog.Logger
 	redisClient   redis.UniversalClient
+	loader        interfaces.ItemLoader[T]
+	negativeTTL   time.Duration
+	dirtyKey      string
+	local         *localCache
+	channel       string
+	instanceId    string
+	tracking      bool
+	trackedClient atomic.Pointer[redis.Client]
+	namespace     string
+	entityName    string
+	eventStream   string
+	eventMaxLen   int64
 }
 
 func ItemCache[T interfaces.Item](keyFormat string, logger *slog.Logger, redisClient redis.UniversalClient) *ItemCacheType[T] {
@@ -26,14 +45,40 @@ func ItemCache[T interfaces.Item](keyFormat string, logger *slog.Logger, redisCl
 	}
 }
 
+// WithLoader turns the cache into a read-through cache: on a miss, Get loads
+// the item with loader and stores it. Ids the loader reports as not found are
+// remembered for negativeTTL so repeated misses don't reach the database; a
+// negativeTTL of 0 or less disables negative caching. Soft deleted items,
+// tombstoned or loaded with DeletedAt, are reported not found and never cached.
+func (cr *ItemCacheType[T]) WithLoader(loader interfaces.ItemLoader[T], negativeTTL time.Duration) *ItemCacheType[T] {
+	cr.loader = loader
+	cr.negativeTTL = negativeTTL
+	return cr
+}
+
 func (cr *ItemCacheType[T]) Get(randId string) (T, *types.PaginationError) {
 	var nilItem T
 	key := fmt.Sprintf(cr.itemKeyFormat, randId)
 
-	result := cr.redisClient.Get(context.TODO(), key)
+	if cr.local != nil {
+		if value, found := cr.local.get(randId); found {
+			item, errorDecode := cr.decode(value)
+			if errorDecode != nil {
+				return nilItem, errorDecode
+			}
+			afterRead(&item)
+			return item, nil
+		}
+	}
+
+	reader, tracked := cr.reader()
+	result := reader.Get(context.TODO(), key)
 
 	if result.Err() != nil {
 		if result.Err() == redis.Nil {
+			if cr.loader != nil {
+				return cr.load(key, randId)
+			}
 			return nilItem, &types.PaginationError{
 				Err:     KEY_NOT_FOUND,
 				Details: "key not found!",
@@ -45,21 +90,11 @@ func (cr *ItemCacheType[T]) Get(randId string) (T, *types.PaginationError) {
 		}
 	}
 
-	var item T
-	errorUnmarshal := json.Unmarshal([]byte(result.Val()), &item)
-
-	parsedTimeCreatedAt, _ := time.Parse(FORMATTED_TIME, item.GetCreatedAtString())
-	parsedTimeUpdatedAt, _ := time.Parse(FORMATTED_TIME, item.GetUpdatedAtString())
-
-	item.SetCreatedAt(parsedTimeCreatedAt)
-	item.SetUpdatedAt(parsedTimeUpdatedAt)
-
-	if errorUnmarshal != nil {
-		return nilItem, &types.PaginationError{
-			Err:     ERROR_PARSE_JSON,
-			Details: errorUnmarshal.Error(),
-		}
+	item, errorDecode := cr.decode(result.Val())
+	if errorDecode != nil {
+		return nilItem, errorDecode
 	}
+	afterRead(&item)
 
 	setExpire := cr.redisClient.Expire(context.TODO(), key, INDIVIDUAL_KEY_TTL)
 	if setExpire.Err() != nil {
@@ -69,60 +104,377 @@ func (cr *ItemCacheType[T]) Get(randId string) (T, *types.PaginationError) {
 		}
 	}
 
+	// with tracking, only reads Redis tracks may populate the local copy
+	if cr.local != nil && (!cr.tracking || tracked) {
+		cr.local.set(randId, result.Val())
+	}
+
 	return item, nil
 }
 
 func (cr *ItemCacheType[T]) Set(item T) *types.PaginationError {
+	return cr.set(item, true)
+}
+
+// fill caches item as read from the source of truth, by the loader or a
+// change stream. Unlike Set it isn't recorded for write-behind, which would
+// persist it back over newer writes.
+func (cr *ItemCacheType[T]) fill(item T) *types.PaginationError {
+	return cr.set(item, false)
+}
+
+// set writes item, recording it for write-behind when dirty is set.
+func (cr *ItemCacheType[T]) set(item T, dirty bool) *types.PaginationError {
 	key := fmt.Sprintf(cr.itemKeyFormat, item.GetRandId())
 
-	createdAtAsString := item.GetCreatedAt().Format(FORMATTED_TIME)
-	updatedAtAsString := item.GetUpdatedAt().Format(FORMATTED_TIME)
+	errorHooks := beforeWrite(&item)
+	if errorHooks != nil {
+		return errorHooks
+	}
 
-	item.SetCreatedAtString(createdAtAsString)
-	item.SetUpdatedAtString(updatedAtAsString)
+	itemInByte, errorEncode := cr.encode(item)
+	if errorEncode != nil {
+		return errorEncode
+	}
 
-	itemInByte, errorMarshalJson := json.Marshal(item)
-	if errorMarshalJson != nil {
-		return &types.PaginationError{
-			Err:     ERROR_MARSHAL_JSON,
-			Details: errorMarshalJson.Error(),
+	if cr.negativeCaching() {
+		// the item exists now, drop any negative result recorded for it
+		deleteNegative := cr.redisClient.Del(context.TODO(), key+negativeKeyTrailing)
+		if deleteNegative.Err() != nil {
+			return &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: deleteNegative.Err().Error(),
+			}
 		}
 	}
 
 	valueAsString := string(itemInByte)
-	setRedis := cr.redisClient.Set(
-		context.TODO(),
-		key,
-		valueAsString,
-		INDIVIDUAL_KEY_TTL,
-	)
-
-	if setRedis.Err() != nil {
+	errorWrite := writeWithEvents(cr.redisClient, false, cr.events(EVENT_SET, item), func(pipe redis.Pipeliner) {
+		pipe.Set(
+			context.TODO(),
+			key,
+			valueAsString,
+			INDIVIDUAL_KEY_TTL,
+		)
+	})
+
+	if errorWrite != nil {
 		return &types.PaginationError{
 			Err:     REDIS_FATAL_ERROR,
-			Details: setRedis.Err().Error(),
+			Details: errorWrite.Error(),
 		}
 	}
 
-	fmt.Println(item.GetUUID())
+	if dirty {
+		errorDirty := cr.markDirty(item.GetRandId(), false)
+		if errorDirty != nil {
+			return errorDirty
+		}
+	}
+
+	if cr.local != nil {
+		if cr.tracking {
+			// Redis only tracks keys we read, so let the next Get repopulate it
+			cr.local.del(item.GetRandId())
+			return nil
+		}
+		cr.local.set(item.GetRandId(), valueAsString)
+		return cr.publishInvalidation(item.GetRandId())
+	}
 
 	return nil
 }
 
 func (cr *ItemCacheType[T]) Del(item T) *types.PaginationError {
+	return cr.del(item, true)
+}
+
+// evict is fill for a deletion read from the source of truth.
+func (cr *ItemCacheType[T]) evict(item T) *types.PaginationError {
+	return cr.del(item, false)
+}
+
+// del deletes item, recording the deletion for write-behind when dirty is
+// set.
+func (cr *ItemCacheType[T]) del(item T, dirty bool) *types.PaginationError {
 	key := fmt.Sprintf(cr.itemKeyFormat, item.GetRandId())
 
-	deleteRedis := cr.redisClient.Del(
-		context.TODO(),
-		key,
-	)
+	errorWrite := writeWithEvents(cr.redisClient, false, cr.events(EVENT_DEL, item), func(pipe redis.Pipeliner) {
+		pipe.Del(
+			context.TODO(),
+			key,
+		)
+	})
 
-	if deleteRedis.Err() != nil {
+	if errorWrite != nil {
 		return &types.PaginationError{
 			Err:     REDIS_FATAL_ERROR,
-			Details: deleteRedis.Err().Error(),
+			Details: errorWrite.Error(),
 		}
 	}
 
+	if dirty {
+		errorDirty := cr.markDirty(item.GetRandId(), true)
+		if errorDirty != nil {
+			return errorDirty
+		}
+	}
+
+	if cr.local != nil {
+		cr.local.del(item.GetRandId())
+		if cr.tracking {
+			return nil
+		}
+		return cr.publishInvalidation(item.GetRandId())
+	}
+
 	return nil
 }
+
+// SoftDel stamps item's DeletedAt and swaps its key for a tombstone kept for
+// TOMBSTONE_TTL, so a reseed racing the deletion can tell the item is gone
+// and Restore can bring it back.
+func (cr *ItemCacheType[T]) SoftDel(item T) *types.PaginationError {
+	key := fmt.Sprintf(cr.itemKeyFormat, item.GetRandId())
+
+	if deletedAt(item).IsZero() {
+		setDeletedAt(item, time.Now().UTC())
+	}
+
+	itemInByte, errorEncode := cr.encode(item)
+	if errorEncode != nil {
+		return errorEncode
+	}
+
+	errorExec := writeWithEvents(cr.redisClient, true, cr.events(EVENT_DEL, item), func(pipe redis.Pipeliner) {
+		pipe.Set(context.TODO(), key+tombstoneKeyTrailing, string(itemInByte), TOMBSTONE_TTL)
+		pipe.Del(context.TODO(), key)
+	})
+	if errorExec != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorExec.Error(),
+			Message: "Failed to tombstone item on Redis",
+		}
+	}
+
+	// the tombstone is what gets persisted, carrying DeletedAt
+	errorDirty := cr.markDirty(item.GetRandId(), false)
+	if errorDirty != nil {
+		return errorDirty
+	}
+
+	if cr.local != nil {
+		cr.local.del(item.GetRandId())
+		if cr.tracking {
+			return nil
+		}
+		return cr.publishInvalidation(item.GetRandId())
+	}
+
+	return nil
+}
+
+// Restore clears DeletedAt of a tombstoned item and writes it back.
+func (cr *ItemCacheType[T]) Restore(randId string) (T, *types.PaginationError) {
+	var nilItem T
+	key := fmt.Sprintf(cr.itemKeyFormat, randId)
+
+	item, errorTombstone := cr.tombstone(randId)
+	if errorTombstone != nil {
+		return nilItem, errorTombstone
+	}
+	setDeletedAt(item, time.Time{})
+
+	errorSet := cr.Set(item)
+	if errorSet != nil {
+		return nilItem, errorSet
+	}
+
+	deleteTombstone := cr.redisClient.Del(context.TODO(), key+tombstoneKeyTrailing)
+	if deleteTombstone.Err() != nil {
+		return nilItem, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: deleteTombstone.Err().Error(),
+		}
+	}
+
+	return item, nil
+}
+
+// tombstone reads the item soft deleted under randId.
+func (cr *ItemCacheType[T]) tombstone(randId string) (T, *types.PaginationError) {
+	var nilItem T
+	key := fmt.Sprintf(cr.itemKeyFormat, randId)
+
+	tombstone := cr.redisClient.Get(context.TODO(), key+tombstoneKeyTrailing)
+	if tombstone.Err() != nil {
+		if tombstone.Err() == redis.Nil {
+			return nilItem, &types.PaginationError{
+				Err:     KEY_NOT_FOUND,
+				Details: "key not found!",
+				Message: "No tombstone left to restore the item from",
+			}
+		}
+		return nilItem, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: tombstone.Err().Error(),
+		}
+	}
+
+	return cr.decode(tombstone.Val())
+}
+
+// Tombstoned reports, for each of randIds, whether it was soft deleted within
+// TOMBSTONE_TTL, in one round trip.
+func (cr *ItemCacheType[T]) Tombstoned(randIds ...string) ([]bool, *types.PaginationError) {
+	exists := make([]*redis.IntCmd, len(randIds))
+	_, errorExec := cr.redisClient.Pipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		for i, randId := range randIds {
+			exists[i] = pipe.Exists(context.TODO(), fmt.Sprintf(cr.itemKeyFormat, randId)+tombstoneKeyTrailing)
+		}
+		return nil
+	})
+	if errorExec != nil {
+		return nil, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorExec.Error(),
+		}
+	}
+
+	tombstoned := make([]bool, len(randIds))
+	for i := range randIds {
+		tombstoned[i] = exists[i].Val() > 0
+	}
+
+	return tombstoned, nil
+}
+
+func (cr *ItemCacheType[T]) load(key string, randId string) (T, *types.PaginationError) {
+	var nilItem T
+
+	if cr.negativeCaching() {
+		negative := cr.redisClient.Exists(context.TODO(), key+negativeKeyTrailing)
+		if negative.Err() != nil {
+			return nilItem, &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: negative.Err().Error(),
+			}
+		}
+		if negative.Val() > 0 {
+			return nilItem, &types.PaginationError{
+				Err:     KEY_NOT_FOUND,
+				Details: "key not found!",
+				Message: "Item recently not found by loader",
+			}
+		}
+	}
+
+	// the database row of a soft deleted item lingers until it's flushed
+	tombstone := cr.redisClient.Exists(context.TODO(), key+tombstoneKeyTrailing)
+	if tombstone.Err() != nil {
+		return nilItem, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: tombstone.Err().Error(),
+		}
+	}
+	if tombstone.Val() > 0 {
+		return nilItem, &types.PaginationError{
+			Err:     KEY_NOT_FOUND,
+			Details: "key not found!",
+			Message: "Item soft deleted",
+		}
+	}
+
+	item, errorLoad := cr.loader(context.TODO(), randId)
+	if errorLoad != nil {
+		if errors.Is(errorLoad, KEY_NOT_FOUND) {
+			if cr.negativeCaching() {
+				setNegative := cr.redisClient.Set(context.TODO(), key+negativeKeyTrailing, "1", cr.negativeTTL)
+				if setNegative.Err() != nil {
+					return nilItem, &types.PaginationError{
+						Err:     REDIS_FATAL_ERROR,
+						Details: setNegative.Err().Error(),
+					}
+				}
+			}
+
+			return nilItem, &types.PaginationError{
+				Err:     KEY_NOT_FOUND,
+				Details: errorLoad.Error(),
+				Message: "Item not found by loader",
+			}
+		}
+
+		return nilItem, &types.PaginationError{
+			Err:     LOADER_FATAL_ERROR,
+			Details: errorLoad.Error(),
+			Message: "Failed to load item on cache miss",
+		}
+	}
+	if !deletedAt(item).IsZero() {
+		return nilItem, &types.PaginationError{
+			Err:     KEY_NOT_FOUND,
+			Details: "key not found!",
+			Message: "Item soft deleted",
+		}
+	}
+
+	errorSet := cr.fill(item)
+	if errorSet != nil {
+		return nilItem, errorSet
+	}
+	afterRead(&item)
+
+	return item, nil
+}
+
+// negativeCaching tells whether ids the loader didn't find are remembered.
+func (cr *ItemCacheType[T]) negativeCaching() bool {
+	return cr.loader != nil && cr.negativeTTL > 0
+}
+
+// encode marshals item, its timestamps in RFC 3339.
+func (cr *ItemCacheType[T]) encode(item T) ([]byte, *types.PaginationError) {
+	itemInByte, errorMarshalJson := json.Marshal(item)
+	if errorMarshalJson != nil {
+		return nil, &types.PaginationError{
+			Err:     ERROR_MARSHAL_JSON,
+			Details: errorMarshalJson.Error(),
+		}
+	}
+
+	return itemInByte, nil
+}
+
+// decode unmarshals an item written by encode, or by earlier releases that
+// stored timestamps as CreatedAtString and UpdatedAtString. Malformed
+// timestamps fail the unmarshal rather than silently zeroing the fields.
+func (cr *ItemCacheType[T]) decode(value string) (T, *types.PaginationError) {
+	var item T
+	var nilItem T
+	errorUnmarshal := json.Unmarshal([]byte(value), &item)
+	if errorUnmarshal != nil {
+		return nilItem, &types.PaginationError{
+			Err:     ERROR_PARSE_JSON,
+			Details: errorUnmarshal.Error(),
+		}
+	}
+
+	legacy, ok := any(item).(legacyTimestamps)
+	if !ok {
+		legacy, ok = any(&item).(legacyTimestamps)
+	}
+	if ok {
+		if errorUpgrade := legacy.upgradeTimestamps(); errorUpgrade != nil {
+			return nilItem, errorUpgrade
+		}
+	}
+
+	return item, nil
+}
+
+// legacyTimestamps is implemented by items embedding Item.
+type legacyTimestamps interface {
+	upgradeTimestamps() *types.PaginationError
+}
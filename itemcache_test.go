@@ -1,7 +1,9 @@
 package commoncrud
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -10,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/lefalya/commoncrud/interfaces"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 type TestStructItemCache struct {
@@ -37,16 +40,16 @@ func TestGet(t *testing.T) {
 
 	dummyItem := TestStructItemCache{
 		Item: &Item{
-			UUID:            uuid.New().String(),
-			RandId:          RandId(),
-			CreatedAtString: currentTime.Format(FORMATTED_TIME),
-			UpdatedAtString: currentTime.Format(FORMATTED_TIME),
+			UUID:      uuid.New().String(),
+			RandId:    RandId(),
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
 		},
 		FirstName: "test",
 		LastName:  "test again",
 	}
-	createdAtAsTime, _ := time.Parse(FORMATTED_TIME, dummyItem.GetCreatedAtString())
-	updatedAtAsTime, _ := time.Parse(FORMATTED_TIME, dummyItem.GetUpdatedAtString())
+	createdAtAsTime := dummyItem.GetCreatedAt()
+	updatedAtAsTime := dummyItem.GetUpdatedAt()
 
 	dummyItemKeyFormat := "student:%s"
 	expectedKey := fmt.Sprintf(dummyItemKeyFormat, dummyItem.GetRandId())
@@ -78,6 +81,361 @@ func TestGet(t *testing.T) {
 		assert.Equal(t, dummyItem.FirstName, item.FirstName)
 		assert.Equal(t, dummyItem.LastName, item.LastName)
 	})
+	t.Run("key not found without loader", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).RedisNil()
+
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient)
+
+		_, err := itemCache.Get(dummyItem.RandId)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, KEY_NOT_FOUND, err.Err)
+	})
+	t.Run("read-through loads and stores item on miss", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).RedisNil()
+		mockRedis.ExpectExists(expectedKey + negativeKeyTrailing).SetVal(0)
+		mockRedis.ExpectExists(expectedKey + tombstoneKeyTrailing).SetVal(0)
+		mockRedis.ExpectDel(expectedKey + negativeKeyTrailing).SetVal(0)
+		mockRedis.Regexp().ExpectSet(expectedKey, `.*`, INDIVIDUAL_KEY_TTL).SetVal("OK")
+
+		var loadedRandId string
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient).
+			WithLoader(func(ctx context.Context, randId string) (TestStructItemCache, error) {
+				loadedRandId = randId
+				return dummyItem, nil
+			}, time.Minute)
+
+		item, err := itemCache.Get(dummyItem.RandId)
+
+		assert.Nil(t, err)
+		assert.Equal(t, dummyItem.RandId, loadedRandId)
+		assert.Equal(t, dummyItem.FirstName, item.FirstName)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("malformed timestamp fails the get", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).SetVal(`{"RandId":"` + dummyItem.RandId + `","createdat":"yesterday"}`)
+
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient)
+
+		_, err := itemCache.Get(dummyItem.RandId)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, ERROR_PARSE_JSON, err.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("pointer items round trip their timestamps", func(t *testing.T) {
+		jsonStringDummyItem, errorMarshal := json.Marshal(dummyItem)
+		assert.Nil(t, errorMarshal)
+
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).SetVal(string(jsonStringDummyItem))
+		mockRedis.ExpectExpire(expectedKey, INDIVIDUAL_KEY_TTL).SetVal(true)
+
+		itemCache := ItemCache[*TestStructItemCache](dummyItemKeyFormat, logger, redisClient)
+
+		item, err := itemCache.Get(dummyItem.RandId)
+
+		assert.Nil(t, err)
+		assert.True(t, createdAtAsTime.Equal(item.GetCreatedAt()))
+		assert.True(t, item.GetDeletedAt().IsZero())
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("legacy string timestamps decode into times", func(t *testing.T) {
+		legacyTime := currentTime.Format(FORMATTED_TIME)
+		legacyItem := `{"UUID":"` + dummyItem.UUID + `","RandId":"` + dummyItem.RandId +
+			`","CreatedAtString":"` + legacyTime + `","UpdatedAtString":"` + legacyTime + `","FirstName":"test"}`
+
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).SetVal(legacyItem)
+		mockRedis.ExpectExpire(expectedKey, INDIVIDUAL_KEY_TTL).SetVal(true)
+
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient)
+
+		item, err := itemCache.Get(dummyItem.RandId)
+
+		assert.Nil(t, err)
+		assert.True(t, createdAtAsTime.Equal(item.GetCreatedAt()))
+		assert.True(t, updatedAtAsTime.Equal(item.GetUpdatedAt()))
+		assert.Empty(t, item.CreatedAtString)
+		assert.Equal(t, "test", item.FirstName)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("malformed legacy timestamp fails the get", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).SetVal(`{"RandId":"` + dummyItem.RandId + `","CreatedAtString":"yesterday"}`)
+
+		itemCache := ItemCache[*TestStructItemCache](dummyItemKeyFormat, logger, redisClient)
+
+		_, err := itemCache.Get(dummyItem.RandId)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, INVALID_TIMESTAMP, err.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("read-through records negative result", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).RedisNil()
+		mockRedis.ExpectExists(expectedKey + negativeKeyTrailing).SetVal(0)
+		mockRedis.ExpectExists(expectedKey + tombstoneKeyTrailing).SetVal(0)
+		mockRedis.ExpectSet(expectedKey+negativeKeyTrailing, "1", time.Minute).SetVal("OK")
+
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient).
+			WithLoader(func(ctx context.Context, randId string) (TestStructItemCache, error) {
+				return TestStructItemCache{}, fmt.Errorf("student %s: %w", randId, KEY_NOT_FOUND)
+			}, time.Minute)
+
+		_, err := itemCache.Get(dummyItem.RandId)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, KEY_NOT_FOUND, err.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("read-through skips loader on cached negative result", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).RedisNil()
+		mockRedis.ExpectExists(expectedKey + negativeKeyTrailing).SetVal(1)
+
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient).
+			WithLoader(func(ctx context.Context, randId string) (TestStructItemCache, error) {
+				t.Fatal("loader must not be called for a cached negative result")
+				return TestStructItemCache{}, nil
+			}, time.Minute)
+
+		_, err := itemCache.Get(dummyItem.RandId)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, KEY_NOT_FOUND, err.Err)
+	})
+	t.Run("read-through without negative TTL never records negative result", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).RedisNil()
+		mockRedis.ExpectExists(expectedKey + tombstoneKeyTrailing).SetVal(0)
+
+		var loads int
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient).
+			WithLoader(func(ctx context.Context, randId string) (TestStructItemCache, error) {
+				loads++
+				return TestStructItemCache{}, fmt.Errorf("student %s: %w", randId, KEY_NOT_FOUND)
+			}, 0)
+
+		_, err := itemCache.Get(dummyItem.RandId)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, KEY_NOT_FOUND, err.Err)
+		assert.Equal(t, 1, loads)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("read-through rejects soft deleted items", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).RedisNil()
+		mockRedis.ExpectExists(expectedKey + negativeKeyTrailing).SetVal(0)
+		mockRedis.ExpectExists(expectedKey + tombstoneKeyTrailing).SetVal(1)
+
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient).
+			WithLoader(func(ctx context.Context, randId string) (TestStructItemCache, error) {
+				t.Fatal("loader must not be called for a tombstoned item")
+				return TestStructItemCache{}, nil
+			}, time.Minute)
+
+		_, err := itemCache.Get(dummyItem.RandId)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, KEY_NOT_FOUND, err.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("read-through doesn't cache soft deleted rows", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).RedisNil()
+		mockRedis.ExpectExists(expectedKey + negativeKeyTrailing).SetVal(0)
+		mockRedis.ExpectExists(expectedKey + tombstoneKeyTrailing).SetVal(0)
+
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient).
+			WithLoader(func(ctx context.Context, randId string) (TestStructItemCache, error) {
+				deleted := TestStructItemCache{Item: &Item{RandId: randId, DeletedAt: currentTime}}
+				return deleted, nil
+			}, time.Minute)
+
+		_, err := itemCache.Get(dummyItem.RandId)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, KEY_NOT_FOUND, err.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("read-through loader failure", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).RedisNil()
+		mockRedis.ExpectExists(expectedKey + negativeKeyTrailing).SetVal(0)
+		mockRedis.ExpectExists(expectedKey + tombstoneKeyTrailing).SetVal(0)
+
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient).
+			WithLoader(func(ctx context.Context, randId string) (TestStructItemCache, error) {
+				return TestStructItemCache{}, errors.New("connection refused")
+			}, time.Minute)
+
+		_, err := itemCache.Get(dummyItem.RandId)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, LOADER_FATAL_ERROR, err.Err)
+	})
+}
+
+func TestLocalCacheItemCache(t *testing.T) {
+	currentTime := time.Now().In(time.UTC)
+
+	dummyItem := TestStructItemCache{
+		Item: &Item{
+			UUID:      uuid.New().String(),
+			RandId:    RandId(),
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+		},
+		FirstName: "test",
+		LastName:  "test again",
+	}
+
+	dummyItemKeyFormat := "student:%s"
+	expectedKey := fmt.Sprintf(dummyItemKeyFormat, dummyItem.GetRandId())
+
+	t.Run("repeated get is served from local cache", func(t *testing.T) {
+		jsonStringDummyItem, errorMarshal := json.Marshal(dummyItem)
+		assert.Nil(t, errorMarshal)
+
+		redisClient, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(expectedKey).SetVal(string(jsonStringDummyItem))
+		mockRedis.ExpectExpire(expectedKey, INDIVIDUAL_KEY_TTL).SetVal(true)
+
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient).
+			WithLocalCache(10, time.Minute, "student:invalidation")
+
+		first, err := itemCache.Get(dummyItem.RandId)
+		assert.Nil(t, err)
+		second, err := itemCache.Get(dummyItem.RandId)
+		assert.Nil(t, err)
+
+		assert.Equal(t, first.FirstName, second.FirstName)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("local copies don't share memory with callers", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient).
+			WithLocalCache(10, time.Minute, "student:invalidation")
+
+		item := TestStructItemCache{
+			Item:      &Item{RandId: dummyItem.RandId, CreatedAt: currentTime, UpdatedAt: currentTime},
+			FirstName: "test",
+		}
+		mockRedis.Regexp().ExpectSet(expectedKey, `.*`, INDIVIDUAL_KEY_TTL).SetVal("OK")
+		mockRedis.ExpectPublish("student:invalidation", itemCache.instanceId+":"+dummyItem.RandId).SetVal(1)
+
+		assert.Nil(t, itemCache.Set(item))
+		item.FirstName = "changed after set"
+		item.UUID = "changed after set"
+
+		first, err := itemCache.Get(dummyItem.RandId)
+		assert.Nil(t, err)
+		assert.Equal(t, "test", first.FirstName)
+		assert.Equal(t, "", first.GetUUID())
+
+		first.Item.UUID = "changed after get"
+		second, err := itemCache.Get(dummyItem.RandId)
+		assert.Nil(t, err)
+		assert.Equal(t, "", second.GetUUID())
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("set and del publish invalidation", func(t *testing.T) {
+		redisClient, mockRedis := redismock.NewClientMock()
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, redisClient).
+			WithLocalCache(10, time.Minute, "student:invalidation")
+		message := itemCache.instanceId + ":" + dummyItem.RandId
+
+		mockRedis.Regexp().ExpectSet(expectedKey, `.*`, INDIVIDUAL_KEY_TTL).SetVal("OK")
+		mockRedis.ExpectPublish("student:invalidation", message).SetVal(1)
+		mockRedis.ExpectDel(expectedKey).SetVal(1)
+		mockRedis.ExpectPublish("student:invalidation", message).SetVal(1)
+
+		assert.Nil(t, itemCache.Set(dummyItem))
+		_, found := itemCache.local.get(dummyItem.RandId)
+		assert.True(t, found)
+
+		assert.Nil(t, itemCache.Del(dummyItem))
+		_, found = itemCache.local.get(dummyItem.RandId)
+		assert.False(t, found)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("invalidation from other instances evicts local copy", func(t *testing.T) {
+		itemCache := ItemCache[TestStructItemCache](dummyItemKeyFormat, logger, nil).
+			WithLocalCache(10, time.Minute, "student:invalidation")
+		itemCache.local.set(dummyItem.RandId, "{}")
+
+		itemCache.invalidate(itemCache.instanceId + ":" + dummyItem.RandId)
+		_, found := itemCache.local.get(dummyItem.RandId)
+		assert.True(t, found)
+
+		itemCache.invalidate("otherinstance:" + dummyItem.RandId)
+		_, found = itemCache.local.get(dummyItem.RandId)
+		assert.False(t, found)
+	})
+}
+
+func TestNewItem(t *testing.T) {
+	t.Run("value item", func(t *testing.T) {
+		item := NewItem(TestStructItemCache{FirstName: "test"})
+		assert.NotEmpty(t, item.GetRandId())
+		assert.Equal(t, time.UTC, item.GetCreatedAt().Location())
+	})
+
+	t.Run("nil pointer item", func(t *testing.T) {
+		item := NewItem[*TestStructItemCache](nil)
+		assert.NotNil(t, item)
+		assert.NotEmpty(t, item.GetRandId())
+		assert.Equal(t, item.GetCreatedAt(), item.GetUpdatedAt())
+	})
+
+	t.Run("pointer item keeps its fields", func(t *testing.T) {
+		item := NewItem(&TestStructItemCache{FirstName: "test"})
+		assert.Equal(t, "test", item.FirstName)
+		assert.NotEmpty(t, item.GetUUID())
+	})
+}
+
+func TestParseTime(t *testing.T) {
+	expected := time.Date(2024, 5, 1, 8, 30, 0, 123456789, time.UTC)
+
+	parsed, errorParse := ParseTime("2024-05-01T08:30:00.123456789Z")
+	assert.Nil(t, errorParse)
+	assert.Equal(t, expected, parsed)
+
+	parsed, errorParse = ParseTime("2024-05-01T10:30:00.123456789+02:00")
+	assert.Nil(t, errorParse)
+	assert.Equal(t, expected, parsed)
+
+	_, errorParse = ParseTime("yesterday")
+	assert.Equal(t, INVALID_TIMESTAMP, errorParse.Err)
+
+	item := &Item{}
+	item.SetCreatedAtString("yesterday")
+	assert.True(t, item.GetCreatedAt().IsZero())
+	item.SetCreatedAtString("2024-05-01T08:30:00.123456789Z")
+	assert.Equal(t, expected, item.GetCreatedAt())
+}
+
+func TestLegacyBsonTimestamps(t *testing.T) {
+	expected := time.Date(2024, 5, 1, 8, 30, 0, 123456789, time.UTC)
+
+	document, errorMarshal := bson.Marshal(bson.M{
+		"randid":    "abc",
+		"createdat": expected.Format(FORMATTED_TIME),
+		"updatedat": expected,
+	})
+	assert.Nil(t, errorMarshal)
+
+	var item TestStructItemCache
+	assert.Nil(t, bson.Unmarshal(document, &item))
+	assert.Equal(t, expected, item.GetCreatedAt())
+	assert.True(t, expected.Truncate(time.Millisecond).Equal(item.GetUpdatedAt()))
 }
 
 func TestSet(t *testing.T) {
@@ -0,0 +1,314 @@
+package commoncrud
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// SET_STATE_ABSENT means nothing is cached for the pagination key.
+	SET_STATE_ABSENT = "absent"
+	// SET_STATE_PARTIAL means only part of the list is cached.
+	SET_STATE_PARTIAL = "partial"
+	// SET_STATE_SETTLED means the whole list is cached.
+	SET_STATE_SETTLED = "settled"
+)
+
+// expireTogether gives the sorted set and its bookkeeping keys the same
+// expiration so they expire as one.
+var expireTogether = redis.NewScript(`
+for i = 1, #KEYS do
+	redis.call("PEXPIRE", KEYS[i], ARGV[1])
+end
+return #KEYS
+`)
+
+// incrementIfExists adjusts a counter without recreating it once it expired.
+var incrementIfExists = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("INCRBY", KEYS[1], ARGV[1])
+end
+return false
+`)
+
+// componentKeys lists the sorted set followed by every bookkeeping key this
+// pagination maintains for it.
+func (pg *PaginationType[T]) componentKeys(key string) []string {
+	keys := []string{key + pg.sortedSetKeyTrailing, key + pg.settledKeyTrailing}
+	if pg.cardinalityKeyTrailing != "" {
+		keys = append(keys, key+pg.cardinalityKeyTrailing)
+	}
+	if pg.highestScoreKeyTrailing != "" {
+		keys = append(keys, key+pg.highestScoreKeyTrailing)
+	}
+	if pg.lowestScoreKeyTrailing != "" {
+		keys = append(keys, key+pg.lowestScoreKeyTrailing)
+	}
+
+	return keys
+}
+
+// bookkeepingKey is the key a sorted set can't be used without: the
+// cardinality for createdat ascending, the threshold score for custom
+// attributes, or "" when there is none.
+func (pg *PaginationType[T]) bookkeepingKey(key string) string {
+	if pg.cardinalityKeyTrailing != "" {
+		return key + pg.cardinalityKeyTrailing
+	}
+	if pg.highestScoreKeyTrailing != "" {
+		return key + pg.highestScoreKeyTrailing
+	}
+	if pg.lowestScoreKeyTrailing != "" {
+		return key + pg.lowestScoreKeyTrailing
+	}
+
+	return ""
+}
+
+func (pg *PaginationType[T]) extendSortedSet(key string) *types.PaginationError {
+	errorSlot := pg.validateSlot(pg.componentKeys(key)...)
+	if errorSlot != nil {
+		return errorSlot
+	}
+
+	extend := expireTogether.Run(
+		context.TODO(),
+		pg.redisClient,
+		pg.componentKeys(key),
+		SORTED_SET_TTL.Milliseconds(),
+	)
+	if extend.Err() != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: extend.Err().Error(),
+			Message: "Failed to extend pagination set expiration on Redis",
+		}
+	}
+
+	return nil
+}
+
+func (pg *PaginationType[T]) adjustCardinality(key string, delta int64) *types.PaginationError {
+	if pg.cardinalityKeyTrailing == "" {
+		return nil
+	}
+
+	adjust := incrementIfExists.Run(context.TODO(), pg.redisClient, []string{key + pg.cardinalityKeyTrailing}, delta)
+	if adjust.Err() != nil && adjust.Err() != redis.Nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: adjust.Err().Error(),
+			Message: "Failed to adjust cardinality on Redis",
+		}
+	}
+
+	return nil
+}
+
+// countListed adjusts by delta the facet counts of paginationParameters and
+// the cardinality of key, once item entered or left the list.
+func (pg *PaginationType[T]) countListed(key string, paginationParameters []string, delta int64) *types.PaginationError {
+	errorFacets := pg.countFacets(paginationParameters, delta)
+	if errorFacets != nil {
+		return errorFacets
+	}
+
+	return pg.adjustCardinality(key, delta)
+}
+
+// membership holds what's read of item before it leaves the list of key: the
+// boundary member closing the cached window, none when the sorted set isn't
+// cached, and the rank of item, redis.Nil when it isn't in the sorted set.
+type membership struct {
+	boundary *redis.ZSliceCmd
+	rank     *redis.IntCmd
+}
+
+// listed tells whether item was in the sorted set.
+func (m membership) listed() bool {
+	return m.rank.Err() == nil
+}
+
+// readMembership queues on pipe the reads of the membership of item in the
+// sorted set of key.
+func (pg *PaginationType[T]) readMembership(pipe redis.Pipeliner, key string, item T) membership {
+	sortedSetKey := key + pg.sortedSetKeyTrailing
+	var read membership
+	if pg.direction == ascending {
+		read.boundary = pipe.ZRangeWithScores(context.TODO(), sortedSetKey, -1, -1)
+	} else {
+		read.boundary = pipe.ZRangeWithScores(context.TODO(), sortedSetKey, 0, 0)
+	}
+	read.rank = pipe.ZRank(context.TODO(), sortedSetKey, pg.Member(item))
+
+	return read
+}
+
+// pastWindow tells whether item, missing from the sorted set read in m, is
+// still listed in the database: the sorted set isn't cached, or item sorts
+// past its boundary. Within the window, item was already removed.
+func (pg *PaginationType[T]) pastWindow(m membership, item T) (bool, *types.PaginationError) {
+	if len(m.boundary.Val()) == 0 {
+		return true, nil
+	}
+	boundary := m.boundary.Val()[0]
+
+	// sorting by id every score is the same, members are ordered instead
+	if pg.attribute == idAttribute {
+		boundaryMember, _ := boundary.Member.(string)
+		if pg.direction == ascending {
+			return pg.Member(item) > boundaryMember, nil
+		}
+		return pg.Member(item) < boundaryMember, nil
+	}
+
+	score := pg.creationScore(item)
+	if !pg.byCreation() {
+		var errorScore *types.PaginationError
+		score, errorScore = pg.scoreOf(item)
+		if errorScore != nil {
+			return false, errorScore
+		}
+	}
+
+	if pg.direction == ascending {
+		return score > boundary.Score, nil
+	}
+	return score < boundary.Score, nil
+}
+
+// countRemoval counts item out of the list of key after planRemove ran,
+// when the ZREM it queued, removed, reports a change. An item missing from
+// the sorted set per m is counted out only when it's past the cached window.
+func (pg *PaginationType[T]) countRemoval(
+	key string,
+	paginationParameters []string,
+	item T,
+	m membership,
+	removed *redis.IntCmd,
+) *types.PaginationError {
+	counted := removed != nil && removed.Val() > 0
+	if !m.listed() {
+		var errorWindow *types.PaginationError
+		counted, errorWindow = pg.pastWindow(m, item)
+		if errorWindow != nil {
+			return errorWindow
+		}
+	}
+	if !counted {
+		return nil
+	}
+
+	return pg.countListed(key, paginationParameters, -1)
+}
+
+// MarkSeeded records the bookkeeping of a freshly seeded sorted set:
+// cardinality is the total number of items of the list in the database,
+// settled tells whether the sorted set now holds all of them. Seeders call it
+// after writing the sorted set.
+func (pg *PaginationType[T]) MarkSeeded(settled bool, cardinality int64, paginationParameters ...string) *types.PaginationError {
+	key, errorKey := pg.baseKey(paginationParameters)
+	if errorKey != nil {
+		return errorKey
+	}
+
+	if pg.cardinalityKeyTrailing != "" {
+		setCardinality := pg.redisClient.Set(
+			context.TODO(),
+			key+pg.cardinalityKeyTrailing,
+			strconv.FormatInt(cardinality, 10),
+			SORTED_SET_TTL,
+		)
+		if setCardinality.Err() != nil {
+			return &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: setCardinality.Err().Error(),
+				Message: "Failed to set cardinality on Redis",
+			}
+		}
+	}
+
+	if pg.highestScoreKeyTrailing != "" || pg.lowestScoreKeyTrailing != "" {
+		_, found, errorRecompute := pg.recomputeThreshold(key)
+		if errorRecompute != nil {
+			return errorRecompute
+		}
+		if !found {
+			return pg.dropSortedSet(key)
+		}
+	}
+
+	if settled {
+		setSettled := pg.redisClient.Set(context.TODO(), key+pg.settledKeyTrailing, "1", SORTED_SET_TTL)
+		if setSettled.Err() != nil {
+			return &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: setSettled.Err().Error(),
+				Message: "Failed to set settled key on Redis",
+			}
+		}
+	} else {
+		deleteSettled := pg.redisClient.Del(context.TODO(), key+pg.settledKeyTrailing)
+		if deleteSettled.Err() != nil {
+			return &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: deleteSettled.Err().Error(),
+				Message: "Failed to delete settled key on Redis",
+			}
+		}
+	}
+
+	return pg.extendSortedSet(key)
+}
+
+// SetState reports whether the sorted set of a pagination key is absent,
+// partially cached or settled. A sorted set missing any of its components is
+// dropped and reported absent, so it gets reseeded as a whole.
+func (pg *PaginationType[T]) SetState(paginationParameters ...string) (string, *types.PaginationError) {
+	key, errorKey := pg.baseKey(paginationParameters)
+	if errorKey != nil {
+		return "", errorKey
+	}
+
+	keys := []string{key + pg.sortedSetKeyTrailing, key + pg.settledKeyTrailing}
+	if bookkeeping := pg.bookkeepingKey(key); bookkeeping != "" {
+		keys = append(keys, bookkeeping)
+	}
+
+	exists := make([]bool, len(keys))
+	anyExists := false
+	for i, component := range keys {
+		componentExists := pg.redisClient.Exists(context.TODO(), component)
+		if componentExists.Err() != nil {
+			return "", &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: componentExists.Err().Error(),
+				Message: "Failed to check pagination set state on Redis",
+			}
+		}
+		exists[i] = componentExists.Val() > 0
+		anyExists = anyExists || exists[i]
+	}
+
+	sortedSetExists, settledExists := exists[0], exists[1]
+	bookkeepingExists := len(exists) < 3 || exists[2]
+
+	if !sortedSetExists || !bookkeepingExists {
+		if anyExists {
+			errorDrop := pg.dropSortedSet(key)
+			if errorDrop != nil {
+				return "", errorDrop
+			}
+		}
+		return SET_STATE_ABSENT, nil
+	}
+
+	if settledExists {
+		return SET_STATE_SETTLED, nil
+	}
+
+	return SET_STATE_PARTIAL, nil
+}
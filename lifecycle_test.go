@@ -0,0 +1,91 @@
+package commoncrud
+
+import (
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkSeeded(t *testing.T) {
+	t.Run("(createdAt ascending) mark settled with cardinality", func(t *testing.T) {
+		sortedSetKey := key + ascendingTrailing + "createdat"
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectSet(sortedSetKey+":cardinality", "12", SORTED_SET_TTL).SetVal("OK")
+		mockRedis.ExpectSet(sortedSetKey+":settled", "1", SORTED_SET_TTL).SetVal("OK")
+		mockRedis.ExpectEvalSha(expireTogether.Hash(), []string{
+			sortedSetKey,
+			sortedSetKey + ":settled",
+			sortedSetKey + ":cardinality",
+		}, SORTED_SET_TTL.Milliseconds()).SetVal(int64(3))
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			ascending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+
+		errorMark := pagination.MarkSeeded(true, 12, brand, category)
+		assert.Nil(t, errorMark)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
+
+func TestSetState(t *testing.T) {
+	sortedSetKey := key + ascendingTrailing + "createdat"
+
+	cases := []struct {
+		name          string
+		sortedSet     int64
+		settled       int64
+		cardinality   int64
+		expectedState string
+		expectDrop    bool
+	}{
+		{"absent", 0, 0, 0, SET_STATE_ABSENT, false},
+		{"partial", 1, 0, 1, SET_STATE_PARTIAL, false},
+		{"settled", 1, 1, 1, SET_STATE_SETTLED, false},
+		{"expired sorted set invalidates the rest", 0, 1, 1, SET_STATE_ABSENT, true},
+		{"expired cardinality invalidates the rest", 1, 1, 0, SET_STATE_ABSENT, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			redisDB, mockRedis := redismock.NewClientMock()
+			mockRedis.ExpectExists(sortedSetKey).SetVal(c.sortedSet)
+			mockRedis.ExpectExists(sortedSetKey + ":settled").SetVal(c.settled)
+			mockRedis.ExpectExists(sortedSetKey + ":cardinality").SetVal(c.cardinality)
+			if c.expectDrop {
+				mockRedis.ExpectDel(
+					sortedSetKey,
+					sortedSetKey+":settled",
+					sortedSetKey+":cardinality",
+				).SetVal(2)
+			}
+
+			pagination := Pagination[Car](
+				"car",
+				"createdat",
+				ascending,
+				[]string{"brands", "category"},
+				itemPerPage,
+				"",
+				logger,
+				redisDB,
+				nil,
+			)
+
+			state, errorState := pagination.SetState(brand, category)
+			assert.Nil(t, errorState)
+			assert.Equal(t, c.expectedState, state)
+			assert.Nil(t, mockRedis.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package commoncrud
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lefalya/commoncrud/types"
+)
+
+type localEntry struct {
+	randId    string
+	value     string
+	expiresAt time.Time
+}
+
+// localCache is a bounded, in-process LRU whose entries also expire after a
+// fixed staleness bound, in case an invalidation message is missed. It keeps
+// items encoded, so callers never share memory with a cached copy.
+type localCache struct {
+	mutex     sync.Mutex
+	size      int
+	staleness time.Duration
+	entries   map[string]*list.Element
+	order     *list.List
+}
+
+func newLocalCache(size int, staleness time.Duration) *localCache {
+	return &localCache{
+		size:      size,
+		staleness: staleness,
+		entries:   make(map[string]*list.Element),
+		order:     list.New(),
+	}
+}
+
+func (lc *localCache) get(randId string) (string, bool) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	element, found := lc.entries[randId]
+	if !found {
+		return "", false
+	}
+
+	entry := element.Value.(*localEntry)
+	if time.Now().After(entry.expiresAt) {
+		lc.order.Remove(element)
+		delete(lc.entries, randId)
+		return "", false
+	}
+
+	lc.order.MoveToFront(element)
+	return entry.value, true
+}
+
+func (lc *localCache) set(randId string, value string) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	expiresAt := time.Now().Add(lc.staleness)
+	if element, found := lc.entries[randId]; found {
+		entry := element.Value.(*localEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		lc.order.MoveToFront(element)
+		return
+	}
+
+	lc.entries[randId] = lc.order.PushFront(&localEntry{
+		randId:    randId,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+
+	if lc.order.Len() > lc.size {
+		oldest := lc.order.Back()
+		lc.order.Remove(oldest)
+		delete(lc.entries, oldest.Value.(*localEntry).randId)
+	}
+}
+
+func (lc *localCache) clear() {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	lc.entries = make(map[string]*list.Element)
+	lc.order.Init()
+}
+
+func (lc *localCache) del(randId string) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	if element, found := lc.entries[randId]; found {
+		lc.order.Remove(element)
+		delete(lc.entries, randId)
+	}
+}
+
+// WithLocalCache puts a bounded in-process LRU of size entries in front of
+// Redis. Set and Del publish the randId on channel so other instances drop
+// their copy; run ListenInvalidation to receive those messages. Entries are
+// never served older than staleness, even if a message is missed.
+func (cr *ItemCacheType[T]) WithLocalCache(size int, staleness time.Duration, channel string) *ItemCacheType[T] {
+	cr.local = newLocalCache(size, staleness)
+	cr.channel = channel
+	cr.instanceId = RandId()
+	return cr
+}
+
+// ListenInvalidation evicts local copies invalidated by other instances until
+// ctx is cancelled. It blocks, so run it in its own goroutine.
+func (cr *ItemCacheType[T]) ListenInvalidation(ctx context.Context) {
+	subscriber := cr.redisClient.Subscribe(ctx, cr.channel)
+	defer subscriber.Close()
+
+	messages := subscriber.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			cr.invalidate(message.Payload)
+		}
+	}
+}
+
+func (cr *ItemCacheType[T]) invalidate(payload string) {
+	instanceId, randId, found := strings.Cut(payload, ":")
+	if !found || instanceId == cr.instanceId {
+		return
+	}
+
+	cr.local.del(randId)
+}
+
+func (cr *ItemCacheType[T]) publishInvalidation(randId string) *types.PaginationError {
+	publish := cr.redisClient.Publish(context.TODO(), cr.channel, cr.instanceId+":"+randId)
+	if publish.Err() != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: publish.Err().Error(),
+			Message: "Failed to publish local cache invalidation",
+		}
+	}
+
+	return nil
+}
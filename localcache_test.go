@@ -0,0 +1,40 @@
+package commoncrud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalCache(t *testing.T) {
+	t.Run("evicts least recently used entry", func(t *testing.T) {
+		cache := newLocalCache(2, time.Minute)
+		cache.set("a", "first")
+		cache.set("b", "second")
+		_, _ = cache.get("a")
+		cache.set("c", "third")
+
+		_, foundB := cache.get("b")
+		itemA, foundA := cache.get("a")
+		assert.False(t, foundB)
+		assert.True(t, foundA)
+		assert.Equal(t, "first", itemA)
+	})
+	t.Run("expires entries after staleness bound", func(t *testing.T) {
+		cache := newLocalCache(2, time.Millisecond)
+		cache.set("a", "first")
+		time.Sleep(5 * time.Millisecond)
+
+		_, found := cache.get("a")
+		assert.False(t, found)
+	})
+	t.Run("delete entry", func(t *testing.T) {
+		cache := newLocalCache(2, time.Minute)
+		cache.set("a", "first")
+		cache.del("a")
+
+		_, found := cache.get("a")
+		assert.False(t, found)
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"math/rand"
 	"os"
 	"reflect"
 	"strings"
@@ -12,6 +11,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/lefalya/commoncrud/interfaces"
+	"github.com/lefalya/commoncrud/types"
 )
 
 const (
@@ -20,6 +20,16 @@ const (
 	SORTED_SET_TTL            = DAY * 2
 	MAXIMUM_AMOUNT_REFERENCES = 5
 	RANDID_LENGTH             = 16
+	RANDID_ALPHABET           = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	RANDID_RESERVE_ATTEMPTS   = 5
+	SEED_LOCK_POLL_INTERVAL   = 50 * time.Millisecond
+	SEED_FENCE_TTL            = SORTED_SET_TTL
+	QUERY_RESULT_TTL          = 30 * time.Second
+	TOMBSTONE_TTL             = DAY
+	EVENT_BATCH_SIZE          = int64(100)
+	EVENT_BLOCK               = 5 * time.Second
+	WRITE_BEHIND_MAX_RETRIES  = 3
+	WRITE_BEHIND_BACKOFF      = 100 * time.Millisecond
 	// Go's reference time, which is Mon Jan 2 15:04:05 MST 2006
 	FORMATTED_TIME = "2006-01-02T15:04:05.000000000Z"
 )
@@ -31,69 +41,147 @@ var (
 	KEY_NOT_FOUND      = errors.New("(commoncrud) Key not found")
 	ERROR_PARSE_JSON   = errors.New("(commoncrud) parse json fatal error!")
 	ERROR_MARSHAL_JSON = errors.New("(commoncrud) error marshal json!")
+	LOADER_FATAL_ERROR = errors.New("(commoncrud) Loader fatal error")
+	RANDID_EXHAUSTED   = errors.New("(commoncrud) Failed to reserve a unique randId")
+	INVALID_ALPHABET   = errors.New("(commoncrud) Invalid randId alphabet")
+	INVALID_TIMESTAMP  = errors.New("(commoncrud) Invalid timestamp")
+	ITEM_INVALID       = errors.New("(commoncrud) Item failed validation")
 	// Pagination errors
 	TOO_MUCH_REFERENCES        = errors.New("(commoncrud) Too much references")
 	NO_VALID_REFERENCES        = errors.New("(commoncrud) No valid references")
 	INVALID_SORTING_ORDER      = errors.New("(commoncrud) Invalid sorting order")
 	MUST_BE_NUMERICAL_DATATYPE = errors.New("(commoncrud) sorting attribute must be in numerical datatype")
 	FOUND_SORTING_BUT_NO_VALUE = errors.New("(commoncrud) Nil value on sorted attribute")
+	SEED_IN_PROGRESS           = errors.New("(commoncrud) Seeding in progress by another process")
+	FILTER_FIELD_NOT_FOUND     = errors.New("(commoncrud) Filter field not found on item")
+	INVALID_SET_QUERY          = errors.New("(commoncrud) Invalid set query")
+	UNKNOWN_FILTER             = errors.New("(commoncrud) Unknown pagination filter")
+	ITEM_DELETED               = errors.New("(commoncrud) Item is deleted")
+	SOFT_DELETE_UNSUPPORTED    = errors.New("(commoncrud) Item cache doesn't support soft delete")
+	CROSS_SLOT_KEYS            = errors.New("(commoncrud) Keys span several cluster slots")
+	INVALID_KEY_PARAMETERS     = errors.New("(commoncrud) Key parameters don't match the key format")
+	// Write-behind errors
+	PERSIST_FATAL_ERROR = errors.New("(commoncrud) Persister fatal error")
+	// Sync errors
+	CHANGE_STREAM_FATAL_ERROR = errors.New("(commoncrud) Change stream fatal error")
+	CHANGE_STREAM_INVALIDATED = errors.New("(commoncrud) Change stream invalidated")
+	PRE_IMAGE_MISSING         = errors.New("(commoncrud) Change is missing its pre-image")
 )
 
-func concatKey(keyFormat string, parameters []string) string {
-	// need to check if parameters is higher than string slots
+// keyParameterEscaper percent-encodes the characters that would let a
+// parameter add key segments, open a hash tag or fake an escape.
+var keyParameterEscaper = strings.NewReplacer(
+	"%", "%25",
+	":", "%3A",
+	"{", "%7B",
+	"}", "%7D",
+)
+
+func concatKey(keyFormat string, parameters []string) (string, *types.PaginationError) {
+	if slots := strings.Count(keyFormat, "%s"); slots != len(parameters) {
+		return "", &types.PaginationError{
+			Err:     INVALID_KEY_PARAMETERS,
+			Details: fmt.Sprintf("%q has %d slots, got %d parameters", keyFormat, slots, len(parameters)),
+			Message: "Failed to build key from pagination parameters",
+		}
+	}
+
 	args := make([]interface{}, len(parameters))
 	for i, v := range parameters {
 		lowercase := strings.ToLower(v)
 		dashed := strings.ReplaceAll(lowercase, " ", "-")
-		args[i] = dashed
+		args[i] = keyParameterEscaper.Replace(dashed)
 	}
 
-	return fmt.Sprintf(keyFormat, args...)
+	return fmt.Sprintf(keyFormat, args...), nil
 }
 
+// RandId generates a randId of RANDID_LENGTH characters of RANDID_ALPHABET
+// from crypto/rand.
 func RandId() string {
-	// Define the characters that can be used in the random string
-	characters := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-
-	// Initialize an empty string to store the result
-	result := make([]byte, RANDID_LENGTH)
-
-	// Generate random characters for the string
-	for i := 0; i < RANDID_LENGTH; i++ {
-		result[i] = characters[rand.Intn(len(characters))]
-	}
-
-	return string(result)
+	return defaultRandIdGenerator()
 }
 
+// initializePointers allocates the nil pointer fields of the struct item
+// points to. item may itself point to a pointer, which is allocated first,
+// so both Car and *Car work as generic item types.
 func initializePointers(item interface{}) {
 	value := reflect.ValueOf(item).Elem()
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return
+	}
 
 	// Iterate through the fields of the struct
 	for i := 0; i < value.NumField(); i++ {
 		field := value.Field(i)
 
 		// Check if the field is a pointer and is nil
-		if field.Kind() == reflect.Ptr && field.IsNil() {
+		if field.Kind() == reflect.Ptr && field.IsNil() && field.CanSet() {
 			// Allocate a new value for the pointer and set it
 			field.Set(reflect.New(field.Type().Elem()))
 		}
 	}
 }
 
+// structTypeOf is the struct type behind T, dereferencing pointer types.
+func structTypeOf[T any]() reflect.Type {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// structValueOf is the struct item holds, dereferencing pointers. ok is
+// false when item is a nil pointer.
+func structValueOf[T any](item T) (value reflect.Value, ok bool) {
+	value = reflect.ValueOf(&item).Elem()
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return value, false
+		}
+		value = value.Elem()
+	}
+	return value, true
+}
+
+// Item carries the identity and timestamps shared by every cached entity.
+// Timestamps marshal natively, as RFC 3339 strings in JSON and as dates in
+// BSON; a zero DeletedAt means the item is live.
+//
+// Documents still holding the older FORMATTED_TIME strings under createdat
+// and updatedat decode through the driver's time codec; MongoChangeStream
+// decodes empty or malformed ones as the zero time.
 type Item struct {
-	UUID            string    `bson:"uuid"`
-	RandId          string    `bson:"randid"`
-	CreatedAt       time.Time `json:"-" bson:"-"`
-	UpdatedAt       time.Time `json:"-" bson:"-"`
-	CreatedAtString string    `bson:"createdat"`
-	UpdatedAtString string    `bson:"updatedat"`
+	UUID      string    `bson:"uuid"`
+	RandId    string    `bson:"randid"`
+	CreatedAt time.Time `json:"createdat" bson:"createdat"`
+	UpdatedAt time.Time `json:"updatedat" bson:"updatedat"`
+	DeletedAt time.Time `json:"deletedat" bson:"deletedat"`
+
+	// Deprecated: CreatedAtString and UpdatedAtString only read cache
+	// entries written before timestamps marshalled natively; decoding moves
+	// them into CreatedAt and UpdatedAt.
+	CreatedAtString string `json:",omitempty" bson:"-"`
+	UpdatedAtString string `json:",omitempty" bson:"-"`
 }
 
 func (i *Item) SetUUID() {
 	i.UUID = uuid.New().String()
 }
 
+// SetUUIDWith sets the UUID from generator, such as UUIDv7 or ULID, instead
+// of a random version 4 UUID.
+func (i *Item) SetUUIDWith(generator interfaces.IdGenerator) {
+	i.UUID = generator()
+}
+
 func (i *Item) GetUUID() string {
 	return i.UUID
 }
@@ -102,6 +190,11 @@ func (i *Item) SetRandId() {
 	i.RandId = RandId()
 }
 
+// SetRandIdWith sets the randId from generator instead of RandId.
+func (i *Item) SetRandIdWith(generator interfaces.IdGenerator) {
+	i.RandId = generator()
+}
+
 func (i *Item) GetRandId() string {
 	return i.RandId
 }
@@ -122,31 +215,123 @@ func (i *Item) GetUpdatedAt() time.Time {
 	return i.UpdatedAt
 }
 
+func (i *Item) SetDeletedAt(time time.Time) {
+	i.DeletedAt = time
+}
+
+func (i *Item) GetDeletedAt() time.Time {
+	return i.DeletedAt
+}
+
+// SetCreatedAtString parses timeString into CreatedAt, leaving it untouched
+// when timeString is malformed; use ParseTime to see the error.
 func (i *Item) SetCreatedAtString(timeString string) {
-	i.CreatedAtString = timeString
+	if parsed, errorParse := ParseTime(timeString); errorParse == nil {
+		i.CreatedAt = parsed
+	}
 }
 
+// SetUpdatedAtString parses timeString into UpdatedAt, leaving it untouched
+// when timeString is malformed; use ParseTime to see the error.
 func (i *Item) SetUpdatedAtString(timeString string) {
-	i.UpdatedAtString = timeString
+	if parsed, errorParse := ParseTime(timeString); errorParse == nil {
+		i.UpdatedAt = parsed
+	}
 }
 
 func (i *Item) GetCreatedAtString() string {
-	return i.CreatedAtString
+	return i.CreatedAt.Format(FORMATTED_TIME)
 }
 
 func (i *Item) GetUpdatedAtString() string {
-	return i.UpdatedAtString
+	return i.UpdatedAt.Format(FORMATTED_TIME)
+}
+
+// upgradeTimestamps moves the legacy string timestamps into CreatedAt and
+// UpdatedAt, failing on strings ParseTime rejects.
+func (i *Item) upgradeTimestamps() *types.PaginationError {
+	if i == nil {
+		return nil
+	}
+
+	if i.CreatedAtString != "" {
+		parsed, errorParse := ParseTime(i.CreatedAtString)
+		if errorParse != nil {
+			return errorParse
+		}
+		if i.CreatedAt.IsZero() {
+			i.CreatedAt = parsed
+		}
+		i.CreatedAtString = ""
+	}
+
+	if i.UpdatedAtString != "" {
+		parsed, errorParse := ParseTime(i.UpdatedAtString)
+		if errorParse != nil {
+			return errorParse
+		}
+		if i.UpdatedAt.IsZero() {
+			i.UpdatedAt = parsed
+		}
+		i.UpdatedAtString = ""
+	}
+
+	return nil
+}
+
+// ParseTime parses a timestamp in FORMATTED_TIME or RFC 3339, normalised to
+// UTC.
+func ParseTime(timeString string) (time.Time, *types.PaginationError) {
+	parsed, errorParse := time.Parse(FORMATTED_TIME, timeString)
+	if errorParse != nil {
+		parsed, errorParse = time.Parse(time.RFC3339Nano, timeString)
+	}
+	if errorParse != nil {
+		return time.Time{}, &types.PaginationError{
+			Err:     INVALID_TIMESTAMP,
+			Details: errorParse.Error(),
+			Message: "Failed to parse timestamp",
+		}
+	}
+
+	return parsed.In(time.UTC), nil
 }
 
 func NewItem[T interfaces.Item](item T) T {
+	return NewItemWith(item, RandId)
+}
+
+// NewItemWith is NewItem drawing the randId from generator, for items
+// embedding Item; other items fall back to their own SetRandId.
+func NewItemWith[T interfaces.Item](item T, generator interfaces.IdGenerator) T {
 	currentTime := time.Now().In(time.UTC)
 
 	initializePointers(&item)
 
 	item.SetUUID()
-	item.SetRandId()
+	if setter, ok := any(item).(interface {
+		SetRandIdWith(generator interfaces.IdGenerator)
+	}); ok {
+		setter.SetRandIdWith(generator)
+	} else {
+		item.SetRandId()
+	}
 	item.SetCreatedAt(currentTime)
 	item.SetUpdatedAt(currentTime)
 
 	return item
 }
+
+// NewSortableItem is NewItem with the UUID drawn from generator, UUIDv7 or
+// ULID, for paginations sorted by "uuid". Items not embedding Item keep
+// their own SetUUID.
+func NewSortableItem[T interfaces.Item](item T, generator interfaces.IdGenerator) T {
+	item = NewItem(item)
+	if setter, ok := any(item).(interface {
+		SetUUIDWith(generator interfaces.IdGenerator)
+	}); ok {
+		setter.SetUUIDWith(generator)
+	}
+
+	return item
+}
@@ -5,6 +5,7 @@
 package mock_interfaces
 
 import (
+	context "context"
 	reflect "reflect"
 	time "time"
 
@@ -50,20 +51,6 @@ func (mr *MockItemMockRecorder) GetCreatedAt() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCreatedAt", reflect.TypeOf((*MockItem)(nil).GetCreatedAt))
 }
 
-// GetCreatedAtString mocks base method.
-func (m *MockItem) GetCreatedAtString() string {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCreatedAtString")
-	ret0, _ := ret[0].(string)
-	return ret0
-}
-
-// GetCreatedAtString indicates an expected call of GetCreatedAtString.
-func (mr *MockItemMockRecorder) GetCreatedAtString() *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCreatedAtString", reflect.TypeOf((*MockItem)(nil).GetCreatedAtString))
-}
-
 // GetRandId mocks base method.
 func (m *MockItem) GetRandId() string {
 	m.ctrl.T.Helper()
@@ -106,20 +93,6 @@ func (mr *MockItemMockRecorder) GetUpdatedAt() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpdatedAt", reflect.TypeOf((*MockItem)(nil).GetUpdatedAt))
 }
 
-// GetUpdatedAtString mocks base method.
-func (m *MockItem) GetUpdatedAtString() string {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUpdatedAtString")
-	ret0, _ := ret[0].(string)
-	return ret0
-}
-
-// GetUpdatedAtString indicates an expected call of GetUpdatedAtString.
-func (mr *MockItemMockRecorder) GetUpdatedAtString() *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpdatedAtString", reflect.TypeOf((*MockItem)(nil).GetUpdatedAtString))
-}
-
 // SetCreatedAt mocks base method.
 func (m *MockItem) SetCreatedAt(time time.Time) {
 	m.ctrl.T.Helper()
@@ -132,18 +105,6 @@ func (mr *MockItemMockRecorder) SetCreatedAt(time interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCreatedAt", reflect.TypeOf((*MockItem)(nil).SetCreatedAt), time)
 }
 
-// SetCreatedAtString mocks base method.
-func (m *MockItem) SetCreatedAtString(timeString string) {
-	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SetCreatedAtString", timeString)
-}
-
-// SetCreatedAtString indicates an expected call of SetCreatedAtString.
-func (mr *MockItemMockRecorder) SetCreatedAtString(timeString interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCreatedAtString", reflect.TypeOf((*MockItem)(nil).SetCreatedAtString), timeString)
-}
-
 // SetRandId mocks base method.
 func (m *MockItem) SetRandId() {
 	m.ctrl.T.Helper()
@@ -180,16 +141,160 @@ func (mr *MockItemMockRecorder) SetUpdatedAt(time interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUpdatedAt", reflect.TypeOf((*MockItem)(nil).SetUpdatedAt), time)
 }
 
-// SetUpdatedAtString mocks base method.
-func (m *MockItem) SetUpdatedAtString(timeString string) {
+// MockDeletable is a mock of Deletable interface.
+type MockDeletable struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeletableMockRecorder
+}
+
+// MockDeletableMockRecorder is the mock recorder for MockDeletable.
+type MockDeletableMockRecorder struct {
+	mock *MockDeletable
+}
+
+// NewMockDeletable creates a new mock instance.
+func NewMockDeletable(ctrl *gomock.Controller) *MockDeletable {
+	mock := &MockDeletable{ctrl: ctrl}
+	mock.recorder = &MockDeletableMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeletable) EXPECT() *MockDeletableMockRecorder {
+	return m.recorder
+}
+
+// GetDeletedAt mocks base method.
+func (m *MockDeletable) GetDeletedAt() time.Time {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletedAt")
+	ret0, _ := ret[0].(time.Time)
+	return ret0
+}
+
+// GetDeletedAt indicates an expected call of GetDeletedAt.
+func (mr *MockDeletableMockRecorder) GetDeletedAt() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletedAt", reflect.TypeOf((*MockDeletable)(nil).GetDeletedAt))
+}
+
+// SetDeletedAt mocks base method.
+func (m *MockDeletable) SetDeletedAt(time time.Time) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetDeletedAt", time)
+}
+
+// SetDeletedAt indicates an expected call of SetDeletedAt.
+func (mr *MockDeletableMockRecorder) SetDeletedAt(time interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeletedAt", reflect.TypeOf((*MockDeletable)(nil).SetDeletedAt), time)
+}
+
+// MockValidator is a mock of Validator interface.
+type MockValidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockValidatorMockRecorder
+}
+
+// MockValidatorMockRecorder is the mock recorder for MockValidator.
+type MockValidatorMockRecorder struct {
+	mock *MockValidator
+}
+
+// NewMockValidator creates a new mock instance.
+func NewMockValidator(ctrl *gomock.Controller) *MockValidator {
+	mock := &MockValidator{ctrl: ctrl}
+	mock.recorder = &MockValidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockValidator) EXPECT() *MockValidatorMockRecorder {
+	return m.recorder
+}
+
+// Validate mocks base method.
+func (m *MockValidator) Validate() error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SetUpdatedAtString", timeString)
+	ret := m.ctrl.Call(m, "Validate")
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// SetUpdatedAtString indicates an expected call of SetUpdatedAtString.
-func (mr *MockItemMockRecorder) SetUpdatedAtString(timeString interface{}) *gomock.Call {
+// Validate indicates an expected call of Validate.
+func (mr *MockValidatorMockRecorder) Validate() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUpdatedAtString", reflect.TypeOf((*MockItem)(nil).SetUpdatedAtString), timeString)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockValidator)(nil).Validate))
+}
+
+// MockBeforeSetter is a mock of BeforeSetter interface.
+type MockBeforeSetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockBeforeSetterMockRecorder
+}
+
+// MockBeforeSetterMockRecorder is the mock recorder for MockBeforeSetter.
+type MockBeforeSetterMockRecorder struct {
+	mock *MockBeforeSetter
+}
+
+// NewMockBeforeSetter creates a new mock instance.
+func NewMockBeforeSetter(ctrl *gomock.Controller) *MockBeforeSetter {
+	mock := &MockBeforeSetter{ctrl: ctrl}
+	mock.recorder = &MockBeforeSetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBeforeSetter) EXPECT() *MockBeforeSetterMockRecorder {
+	return m.recorder
+}
+
+// BeforeSet mocks base method.
+func (m *MockBeforeSetter) BeforeSet() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "BeforeSet")
+}
+
+// BeforeSet indicates an expected call of BeforeSet.
+func (mr *MockBeforeSetterMockRecorder) BeforeSet() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeforeSet", reflect.TypeOf((*MockBeforeSetter)(nil).BeforeSet))
+}
+
+// MockAfterGetter is a mock of AfterGetter interface.
+type MockAfterGetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockAfterGetterMockRecorder
+}
+
+// MockAfterGetterMockRecorder is the mock recorder for MockAfterGetter.
+type MockAfterGetterMockRecorder struct {
+	mock *MockAfterGetter
+}
+
+// NewMockAfterGetter creates a new mock instance.
+func NewMockAfterGetter(ctrl *gomock.Controller) *MockAfterGetter {
+	mock := &MockAfterGetter{ctrl: ctrl}
+	mock.recorder = &MockAfterGetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAfterGetter) EXPECT() *MockAfterGetterMockRecorder {
+	return m.recorder
+}
+
+// AfterGet mocks base method.
+func (m *MockAfterGetter) AfterGet() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AfterGet")
+}
+
+// AfterGet indicates an expected call of AfterGet.
+func (mr *MockAfterGetterMockRecorder) AfterGet() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AfterGet", reflect.TypeOf((*MockAfterGetter)(nil).AfterGet))
 }
 
 // MockPagination is a mock of Pagination interface.
@@ -255,9 +360,9 @@ func (mr *MockPaginationMockRecorder[T]) FetchAll(processor interface{}, paginat
 }
 
 // FetchLinked mocks base method.
-func (m *MockPagination[T]) FetchLinked(references []string, processor interfaces.PaginationProcessor[T], paginationParameters ...string) ([]T, *types.PaginationError) {
+func (m *MockPagination[T]) FetchLinked(references []string, processor interfaces.PaginationProcessor[T], seeder interfaces.LinkedSeedFunc[T], paginationParameters ...string) ([]T, *types.PaginationError) {
 	m.ctrl.T.Helper()
-	varargs := []interface{}{references, processor}
+	varargs := []interface{}{references, processor, seeder}
 	for _, a := range paginationParameters {
 		varargs = append(varargs, a)
 	}
@@ -268,9 +373,9 @@ func (m *MockPagination[T]) FetchLinked(references []string, processor interface
 }
 
 // FetchLinked indicates an expected call of FetchLinked.
-func (mr *MockPaginationMockRecorder[T]) FetchLinked(references, processor interface{}, paginationParameters ...interface{}) *gomock.Call {
+func (mr *MockPaginationMockRecorder[T]) FetchLinked(references, processor, seeder interface{}, paginationParameters ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]interface{}{references, processor}, paginationParameters...)
+	varargs := append([]interface{}{references, processor, seeder}, paginationParameters...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchLinked", reflect.TypeOf((*MockPagination[T])(nil).FetchLinked), varargs...)
 }
 
@@ -347,9 +452,9 @@ func (mr *MockPaginationMockRecorder[T]) SeedCardinality(paginationParameters ..
 }
 
 // SeedLinked mocks base method.
-func (m *MockPagination[T]) SeedLinked(lastItem T, processor interfaces.SeedProcessor[T], paginationParameters ...string) ([]T, *types.PaginationError) {
+func (m *MockPagination[T]) SeedLinked(lastItem T, seeder interfaces.LinkedSeedFunc[T], paginationParameters ...string) ([]T, *types.PaginationError) {
 	m.ctrl.T.Helper()
-	varargs := []interface{}{lastItem, processor}
+	varargs := []interface{}{lastItem, seeder}
 	for _, a := range paginationParameters {
 		varargs = append(varargs, a)
 	}
@@ -360,9 +465,9 @@ func (m *MockPagination[T]) SeedLinked(lastItem T, processor interfaces.SeedProc
 }
 
 // SeedLinked indicates an expected call of SeedLinked.
-func (mr *MockPaginationMockRecorder[T]) SeedLinked(lastItem, processor interface{}, paginationParameters ...interface{}) *gomock.Call {
+func (mr *MockPaginationMockRecorder[T]) SeedLinked(lastItem, seeder interface{}, paginationParameters ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]interface{}{lastItem, processor}, paginationParameters...)
+	varargs := append([]interface{}{lastItem, seeder}, paginationParameters...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SeedLinked", reflect.TypeOf((*MockPagination[T])(nil).SeedLinked), varargs...)
 }
 
@@ -483,3 +588,205 @@ func (mr *MockItemCacheMockRecorder[T]) Set(item interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockItemCache[T])(nil).Set), item)
 }
+
+// MockSoftDeleter is a mock of SoftDeleter interface.
+type MockSoftDeleter[T interfaces.Item] struct {
+	ctrl     *gomock.Controller
+	recorder *MockSoftDeleterMockRecorder[T]
+}
+
+// MockSoftDeleterMockRecorder is the mock recorder for MockSoftDeleter.
+type MockSoftDeleterMockRecorder[T interfaces.Item] struct {
+	mock *MockSoftDeleter[T]
+}
+
+// NewMockSoftDeleter creates a new mock instance.
+func NewMockSoftDeleter[T interfaces.Item](ctrl *gomock.Controller) *MockSoftDeleter[T] {
+	mock := &MockSoftDeleter[T]{ctrl: ctrl}
+	mock.recorder = &MockSoftDeleterMockRecorder[T]{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSoftDeleter[T]) EXPECT() *MockSoftDeleterMockRecorder[T] {
+	return m.recorder
+}
+
+// Restore mocks base method.
+func (m *MockSoftDeleter[T]) Restore(randId string) (T, *types.PaginationError) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", randId)
+	ret0, _ := ret[0].(T)
+	ret1, _ := ret[1].(*types.PaginationError)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockSoftDeleterMockRecorder[T]) Restore(randId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockSoftDeleter[T])(nil).Restore), randId)
+}
+
+// SoftDel mocks base method.
+func (m *MockSoftDeleter[T]) SoftDel(item T) *types.PaginationError {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SoftDel", item)
+	ret0, _ := ret[0].(*types.PaginationError)
+	return ret0
+}
+
+// SoftDel indicates an expected call of SoftDel.
+func (mr *MockSoftDeleterMockRecorder[T]) SoftDel(item interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SoftDel", reflect.TypeOf((*MockSoftDeleter[T])(nil).SoftDel), item)
+}
+
+// Tombstoned mocks base method.
+func (m *MockSoftDeleter[T]) Tombstoned(randIds ...string) ([]bool, *types.PaginationError) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range randIds {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Tombstoned", varargs...)
+	ret0, _ := ret[0].([]bool)
+	ret1, _ := ret[1].(*types.PaginationError)
+	return ret0, ret1
+}
+
+// Tombstoned indicates an expected call of Tombstoned.
+func (mr *MockSoftDeleterMockRecorder[T]) Tombstoned(randIds ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Tombstoned", reflect.TypeOf((*MockSoftDeleter[T])(nil).Tombstoned), randIds...)
+}
+
+// MockPersister is a mock of Persister interface.
+type MockPersister[T interfaces.Item] struct {
+	ctrl     *gomock.Controller
+	recorder *MockPersisterMockRecorder[T]
+}
+
+// MockPersisterMockRecorder is the mock recorder for MockPersister.
+type MockPersisterMockRecorder[T interfaces.Item] struct {
+	mock *MockPersister[T]
+}
+
+// NewMockPersister creates a new mock instance.
+func NewMockPersister[T interfaces.Item](ctrl *gomock.Controller) *MockPersister[T] {
+	mock := &MockPersister[T]{ctrl: ctrl}
+	mock.recorder = &MockPersisterMockRecorder[T]{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPersister[T]) EXPECT() *MockPersisterMockRecorder[T] {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockPersister[T]) Delete(randIds []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", randIds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockPersisterMockRecorder[T]) Delete(randIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPersister[T])(nil).Delete), randIds)
+}
+
+// Persist mocks base method.
+func (m *MockPersister[T]) Persist(items []T) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Persist", items)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Persist indicates an expected call of Persist.
+func (mr *MockPersisterMockRecorder[T]) Persist(items interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Persist", reflect.TypeOf((*MockPersister[T])(nil).Persist), items)
+}
+
+// MockChangeStream is a mock of ChangeStream interface.
+type MockChangeStream[T interfaces.Item] struct {
+	ctrl     *gomock.Controller
+	recorder *MockChangeStreamMockRecorder[T]
+}
+
+// MockChangeStreamMockRecorder is the mock recorder for MockChangeStream.
+type MockChangeStreamMockRecorder[T interfaces.Item] struct {
+	mock *MockChangeStream[T]
+}
+
+// NewMockChangeStream creates a new mock instance.
+func NewMockChangeStream[T interfaces.Item](ctrl *gomock.Controller) *MockChangeStream[T] {
+	mock := &MockChangeStream[T]{ctrl: ctrl}
+	mock.recorder = &MockChangeStreamMockRecorder[T]{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockChangeStream[T]) EXPECT() *MockChangeStreamMockRecorder[T] {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockChangeStream[T]) Close(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockChangeStreamMockRecorder[T]) Close(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockChangeStream[T])(nil).Close), ctx)
+}
+
+// Current mocks base method.
+func (m *MockChangeStream[T]) Current() (types.SourceChange[T], error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Current")
+	ret0, _ := ret[0].(types.SourceChange[T])
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Current indicates an expected call of Current.
+func (mr *MockChangeStreamMockRecorder[T]) Current() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Current", reflect.TypeOf((*MockChangeStream[T])(nil).Current))
+}
+
+// Err mocks base method.
+func (m *MockChangeStream[T]) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Err indicates an expected call of Err.
+func (mr *MockChangeStreamMockRecorder[T]) Err() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockChangeStream[T])(nil).Err))
+}
+
+// Next mocks base method.
+func (m *MockChangeStream[T]) Next(ctx context.Context) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Next", ctx)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Next indicates an expected call of Next.
+func (mr *MockChangeStreamMockRecorder[T]) Next(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Next", reflect.TypeOf((*MockChangeStream[T])(nil).Next), ctx)
+}
@@ -0,0 +1,159 @@
+//go:build mongo
+
+package commoncrud
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/lefalya/commoncrud/interfaces"
+	"github.com/lefalya/commoncrud/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoChangeStream opens change streams on collection for Syncer, narrowed
+// by pipeline. Updates carry the current document; deletions carry the
+// pre-image telling which item went away, so pre-images must be enabled on
+// the collection (MongoDB 6.0+): the stream fails on changes missing one.
+func MongoChangeStream[T interfaces.Item](collection *mongo.Collection, pipeline mongo.Pipeline) interfaces.ChangeStreamOpener[T] {
+	return func(ctx context.Context, resumeToken []byte) (interfaces.ChangeStream[T], error) {
+		opts := options.ChangeStream().
+			SetFullDocument(options.UpdateLookup).
+			SetFullDocumentBeforeChange(options.Required)
+		if resumeToken != nil {
+			opts.SetResumeAfter(bson.Raw(resumeToken))
+		}
+
+		if pipeline == nil {
+			pipeline = mongo.Pipeline{}
+		}
+		stream, errorWatch := collection.Watch(ctx, pipeline, opts)
+		if errorWatch != nil {
+			return nil, errorWatch
+		}
+
+		return &mongoChangeStream[T]{stream: stream}, nil
+	}
+}
+
+type mongoChangeStream[T interfaces.Item] struct {
+	stream *mongo.ChangeStream
+}
+
+func (ms *mongoChangeStream[T]) Next(ctx context.Context) bool {
+	return ms.stream.Next(ctx)
+}
+
+func (ms *mongoChangeStream[T]) Current() (types.SourceChange[T], error) {
+	var event struct {
+		OperationType            string   `bson:"operationType"`
+		FullDocument             bson.Raw `bson:"fullDocument"`
+		FullDocumentBeforeChange bson.Raw `bson:"fullDocumentBeforeChange"`
+	}
+	errorDecode := ms.stream.Decode(&event)
+	if errorDecode != nil {
+		return types.SourceChange[T]{}, errorDecode
+	}
+
+	change := types.SourceChange[T]{
+		Operation:   event.OperationType,
+		ResumeToken: []byte(ms.stream.ResumeToken()),
+	}
+
+	switch event.OperationType {
+	case CHANGE_DELETE:
+		if len(event.FullDocumentBeforeChange) == 0 {
+			return types.SourceChange[T]{}, PRE_IMAGE_MISSING
+		}
+
+		before, errorUnmarshal := unmarshalDocument[T](event.FullDocumentBeforeChange)
+		if errorUnmarshal != nil {
+			return types.SourceChange[T]{}, errorUnmarshal
+		}
+		change.Item = before
+		change.RandId = before.GetRandId()
+	case CHANGE_INSERT, CHANGE_UPDATE, CHANGE_REPLACE:
+		// an update whose document was deleted since has no full document
+		// left, its pre-image still tells which item it was
+		if len(event.FullDocument) == 0 {
+			if len(event.FullDocumentBeforeChange) == 0 {
+				return change, nil
+			}
+
+			before, errorUnmarshal := unmarshalDocument[T](event.FullDocumentBeforeChange)
+			if errorUnmarshal != nil {
+				return types.SourceChange[T]{}, errorUnmarshal
+			}
+			change.RandId = before.GetRandId()
+			return change, nil
+		}
+
+		item, errorUnmarshal := unmarshalDocument[T](event.FullDocument)
+		if errorUnmarshal != nil {
+			return types.SourceChange[T]{}, errorUnmarshal
+		}
+		change.Item = item
+		change.RandId = item.GetRandId()
+	}
+
+	return change, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// documentRegistry decodes changed documents like the default registry,
+// except for timestamps, see tolerantTimeDecoder.
+var documentRegistry = func() *bsoncodec.Registry {
+	registry := bson.NewRegistry()
+	registry.RegisterTypeDecoder(timeType, bsoncodec.ValueDecoderFunc(tolerantTimeDecoder))
+	return registry
+}()
+
+// tolerantTimeDecoder decodes timestamps with the driver's time codec, except
+// strings, which older writers left empty or in formats the codec rejects:
+// those it can't parse as RFC 3339 decode as the zero time instead of failing
+// the whole document.
+func tolerantTimeDecoder(dc bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	if !val.CanSet() || val.Type() != timeType {
+		return bsoncodec.ValueDecoderError{Name: "tolerantTimeDecoder", Types: []reflect.Type{timeType}, Received: val}
+	}
+	if vr.Type() != bsontype.String {
+		decoder, errorLookup := bson.DefaultRegistry.LookupDecoder(timeType)
+		if errorLookup != nil {
+			return errorLookup
+		}
+		return decoder.DecodeValue(dc, vr, val)
+	}
+
+	timeString, errorRead := vr.ReadString()
+	if errorRead != nil {
+		return errorRead
+	}
+
+	var decoded time.Time
+	if parsed, errorParse := time.Parse(time.RFC3339Nano, timeString); errorParse == nil {
+		decoded = parsed.UTC()
+	}
+	val.Set(reflect.ValueOf(decoded))
+	return nil
+}
+
+func unmarshalDocument[T interfaces.Item](document bson.Raw) (T, error) {
+	var item T
+	errorUnmarshal := bson.UnmarshalWithRegistry(documentRegistry, document, &item)
+	return item, errorUnmarshal
+}
+
+func (ms *mongoChangeStream[T]) Err() error {
+	return ms.stream.Err()
+}
+
+func (ms *mongoChangeStream[T]) Close(ctx context.Context) error {
+	return ms.stream.Close(ctx)
+}
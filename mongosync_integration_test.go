@@ -0,0 +1,73 @@
+//go:build mongo
+
+package commoncrud
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestSyncerIntegration needs MONGO_URI pointing at a replica set, change
+// streams aren't available on a standalone server, and REDIS_HOST.
+func TestSyncerIntegration(t *testing.T) {
+	mongoURI := os.Getenv("MONGO_URI")
+	if mongoURI == "" {
+		t.Skip("MONGO_URI environment variable not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, errorConnect := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	assert.Nil(t, errorConnect)
+	defer client.Disconnect(context.TODO())
+
+	// deletions are read from pre-images, see MongoChangeStream
+	database := client.Database("commoncrud")
+	assert.Nil(t, database.Collection("syncer").Drop(ctx))
+	assert.Nil(t, database.CreateCollection(ctx, "syncer",
+		options.CreateCollection().SetChangeStreamPreAndPostImages(bson.M{"enabled": true})))
+	collection := database.Collection("syncer")
+	redisClient := connectRedis()
+
+	itemCache := ItemCache[Car]("syncer:car:%s", logger, redisClient)
+	syncer := Syncer[Car](
+		Registry[Car](itemCache, logger, redisClient),
+		MongoChangeStream[Car](collection, nil),
+		"syncer:car:resumetoken",
+		logger,
+		redisClient,
+	)
+
+	runCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.Nil(t, syncer.Run(runCtx))
+	}()
+	// let the change stream open before writing
+	time.Sleep(time.Second)
+
+	synced := NewItem(Car{Brand: brand, Category: category})
+	_, errorInsert := collection.InsertOne(ctx, synced)
+	assert.Nil(t, errorInsert)
+
+	assert.Eventually(t, func() bool {
+		_, errorGet := itemCache.Get(synced.GetRandId())
+		return errorGet == nil
+	}, 10*time.Second, 100*time.Millisecond)
+
+	stop()
+	<-done
+
+	resumeToken, errorToken := syncer.ResumeToken()
+	assert.Nil(t, errorToken)
+	assert.NotEmpty(t, resumeToken)
+}
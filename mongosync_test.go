@@ -0,0 +1,46 @@
+//go:build mongo
+
+package commoncrud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestUnmarshalDocument(t *testing.T) {
+	expected := time.Date(2024, 5, 1, 8, 30, 0, 123456789, time.UTC)
+
+	t.Run("empty and malformed timestamps decode as zero time", func(t *testing.T) {
+		document, errorMarshal := bson.Marshal(bson.M{
+			"randid":    "abc",
+			"createdat": "",
+			"updatedat": "yesterday",
+			"deletedat": expected,
+			"firstname": "test",
+		})
+		assert.Nil(t, errorMarshal)
+
+		item, errorUnmarshal := unmarshalDocument[TestStructItemCache](document)
+		assert.Nil(t, errorUnmarshal)
+		assert.Equal(t, "abc", item.GetRandId())
+		assert.Equal(t, "test", item.FirstName)
+		assert.True(t, item.GetCreatedAt().IsZero())
+		assert.True(t, item.GetUpdatedAt().IsZero())
+		assert.True(t, expected.Truncate(time.Millisecond).Equal(item.GetDeletedAt()))
+	})
+
+	t.Run("legacy string timestamps decode into times", func(t *testing.T) {
+		document, errorMarshal := bson.Marshal(bson.M{
+			"randid":    "abc",
+			"createdat": expected.Format(FORMATTED_TIME),
+		})
+		assert.Nil(t, errorMarshal)
+
+		item, errorUnmarshal := unmarshalDocument[*TestStructItemCache](document)
+		assert.Nil(t, errorUnmarshal)
+		assert.Equal(t, expected, item.GetCreatedAt())
+	})
+}
@@ -0,0 +1,198 @@
+package commoncrud
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const namespaceScanCount = 1000
+
+// globEscaper escapes the characters SCAN MATCH treats as a pattern.
+var globEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"?", `\?`,
+	"[", `\[`,
+	"]", `\]`,
+)
+
+// namespaced prefixes key with the namespace segment, if any.
+func namespaced(namespace string, key string) string {
+	if namespace == "" {
+		return key
+	}
+
+	return namespace + ":" + key
+}
+
+// namespacedFormat is namespaced for key formats fed to fmt.Sprintf.
+func namespacedFormat(namespace string, keyFormat string) string {
+	return namespaced(strings.ReplaceAll(namespace, "%", "%%"), keyFormat)
+}
+
+type namespaceContextKey struct{}
+
+// ContextWithNamespace returns a copy of ctx carrying namespace, typically
+// the tenant of a request, see WithNamespaceFrom.
+func ContextWithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceContextKey{}, namespace)
+}
+
+// NamespaceFromContext returns the namespace ctx carries, if any.
+func NamespaceFromContext(ctx context.Context) (string, bool) {
+	namespace, ok := ctx.Value(namespaceContextKey{}).(string)
+	return namespace, ok && namespace != ""
+}
+
+// unnamespacedFormat strips the namespace segment off keyFormat.
+func unnamespacedFormat(namespace string, keyFormat string) string {
+	return strings.TrimPrefix(keyFormat, namespacedFormat(namespace, ""))
+}
+
+// WithNamespace returns a copy of cr prefixing every item key with
+// namespace, typically a tenant id, in place of the namespace of cr; cr is
+// left as it is. The copy has no local copy of its own, see WithLocalCache
+// and EnableClientTracking, and records dirty items in its own dirty set,
+// flushed by a WriteBehind built for it.
+func (cr *ItemCacheType[T]) WithNamespace(namespace string) *ItemCacheType[T] {
+	namespace = keyParameterEscaper.Replace(namespace)
+	if namespace == cr.namespace {
+		return cr
+	}
+
+	scoped := &ItemCacheType[T]{
+		itemKeyFormat: namespacedFormat(namespace, unnamespacedFormat(cr.namespace, cr.itemKeyFormat)),
+		logger:        cr.logger,
+		redisClient:   cr.redisClient,
+		loader:        cr.loader,
+		negativeTTL:   cr.negativeTTL,
+		namespace:     namespace,
+		entityName:    cr.entityName,
+		eventStream:   cr.eventStream,
+		eventMaxLen:   cr.eventMaxLen,
+	}
+	if cr.dirtyKey != "" {
+		scoped.dirtyKey = fmt.Sprintf(scoped.itemKeyFormat, dirtyKeyName)
+	}
+
+	return scoped
+}
+
+// WithNamespaceFrom is WithNamespace with the namespace ctx carries, see
+// ContextWithNamespace. Without one, cr is returned as it is.
+func (cr *ItemCacheType[T]) WithNamespaceFrom(ctx context.Context) *ItemCacheType[T] {
+	if namespace, ok := NamespaceFromContext(ctx); ok {
+		return cr.WithNamespace(namespace)
+	}
+
+	return cr
+}
+
+// WithNamespace returns a copy of pg prefixing every pagination key with
+// namespace, typically a tenant id, in place of the namespace of pg; pg is
+// left as it is. The item cache the pagination built itself is namespaced
+// too; an injected one has to be configured separately.
+func (pg *PaginationType[T]) WithNamespace(namespace string) *PaginationType[T] {
+	escaped := keyParameterEscaper.Replace(namespace)
+	if escaped == pg.namespace {
+		return pg
+	}
+
+	scoped := *pg
+	scoped.namespace = escaped
+	scoped.paginationRedisFormat = namespacedFormat(escaped, unnamespacedFormat(pg.namespace, pg.paginationRedisFormat))
+	if itemCache, ok := pg.itemCache.(*ItemCacheType[T]); ok && pg.ownsItemCache {
+		scoped.itemCache = itemCache.WithNamespace(namespace)
+	}
+
+	return &scoped
+}
+
+// WithNamespaceFrom is WithNamespace with the namespace ctx carries, see
+// ContextWithNamespace. Without one, pg is returned as it is.
+func (pg *PaginationType[T]) WithNamespaceFrom(ctx context.Context) *PaginationType[T] {
+	if namespace, ok := NamespaceFromContext(ctx); ok {
+		return pg.WithNamespace(namespace)
+	}
+
+	return pg
+}
+
+// ScanNamespace walks every key under namespace with SCAN, on every master
+// when redisClient is a cluster client, and hands them to fn batch by batch.
+// Keys laid out for Redis Cluster, see WithHashTag, open with the namespace
+// inside their hash tag and are walked too.
+func ScanNamespace(
+	ctx context.Context,
+	redisClient redis.UniversalClient,
+	namespace string,
+	fn func(client redis.UniversalClient, keys []string) error,
+) *types.PaginationError {
+	prefix := globEscaper.Replace(keyParameterEscaper.Replace(namespace)) + ":*"
+	matches := []string{prefix, "{" + prefix}
+
+	scan := func(ctx context.Context, client redis.UniversalClient) error {
+		for _, match := range matches {
+			var cursor uint64
+			for {
+				keys, next, errorScan := client.Scan(ctx, cursor, match, namespaceScanCount).Result()
+				if errorScan != nil {
+					return errorScan
+				}
+				if len(keys) > 0 {
+					if errorFn := fn(client, keys); errorFn != nil {
+						return errorFn
+					}
+				}
+				if next == 0 {
+					break
+				}
+				cursor = next
+			}
+		}
+		return nil
+	}
+
+	var errorScan error
+	if cluster, ok := redisClient.(*redis.ClusterClient); ok {
+		errorScan = cluster.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+			return scan(ctx, master)
+		})
+	} else {
+		errorScan = scan(ctx, redisClient)
+	}
+
+	if errorScan != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorScan.Error(),
+			Message: "Failed to scan namespace keys on Redis",
+		}
+	}
+
+	return nil
+}
+
+// PurgeNamespace unlinks every key under namespace and returns how many were
+// removed.
+func PurgeNamespace(ctx context.Context, redisClient redis.UniversalClient, namespace string) (int64, *types.PaginationError) {
+	if namespace == "" {
+		return 0, &types.PaginationError{
+			Err:     INVALID_KEY_PARAMETERS,
+			Message: "Refusing to purge an empty namespace",
+		}
+	}
+
+	var purged int64
+	errorScan := ScanNamespace(ctx, redisClient, namespace, func(client redis.UniversalClient, keys []string) error {
+		unlinked, errorUnlink := client.Unlink(ctx, keys...).Result()
+		purged += unlinked
+		return errorUnlink
+	})
+
+	return purged, errorScan
+}
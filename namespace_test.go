@@ -0,0 +1,193 @@
+package commoncrud
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+	mock_interfaces "github.com/lefalya/commoncrud/mocks"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespace(t *testing.T) {
+	t.Run("item keys are prefixed with the namespace", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		itemCache := ItemCache[Car](itemKeyFormat, logger, redisDB).WithNamespace("tenant-a")
+
+		carInByte, errorMarshal := json.Marshal(car)
+		assert.Nil(t, errorMarshal)
+		mockRedis.ExpectGet("tenant-a:car:" + car.GetRandId()).SetVal(string(carInByte))
+		mockRedis.ExpectExpire("tenant-a:car:"+car.GetRandId(), INDIVIDUAL_KEY_TTL).SetVal(true)
+
+		_, errorGet := itemCache.Get(car.GetRandId())
+		assert.Nil(t, errorGet)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("pagination keys are prefixed with the namespace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard("tenant%3Aa:" + key + descendingTrailing + "createdat").SetVal(0)
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		).WithNamespace("tenant:a")
+		pagination.itemCache = itemCache
+
+		errorAddItem := pagination.AddItem(car, brand, category)
+		assert.Nil(t, errorAddItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("keep an injected item cache out of the pagination namespace", func(t *testing.T) {
+		itemCache := ItemCache[Car](itemKeyFormat, logger, nil)
+		Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			nil,
+			itemCache,
+		).WithNamespace("tenant-a")
+
+		assert.Equal(t, itemKeyFormat, itemCache.itemKeyFormat)
+	})
+
+	t.Run("namespace taken from the context", func(t *testing.T) {
+		ctx := ContextWithNamespace(context.TODO(), "tenant-a")
+
+		itemCache := ItemCache[Car](itemKeyFormat, logger, nil).WithNamespaceFrom(ctx)
+		assert.Equal(t, "tenant-a:"+itemKeyFormat, itemCache.itemKeyFormat)
+
+		unscoped := ItemCache[Car](itemKeyFormat, logger, nil).WithNamespaceFrom(context.TODO())
+		assert.Equal(t, itemKeyFormat, unscoped.itemKeyFormat)
+	})
+
+	t.Run("write-behind flushes the dirty set of the namespaced cache", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		itemCache := ItemCache[Car](itemKeyFormat, logger, redisDB).WithNamespace("tenant-a")
+		writeBehind := WriteBehind[Car](itemCache, nil, 10, logger, redisDB)
+
+		mockRedis.ExpectZCard("tenant-a:car:" + dirtyKeyName).SetVal(0)
+		mockRedis.ExpectZCard("tenant-a:car:" + dirtyKeyName + deadLetterKeyTrailing).SetVal(0)
+		mockRedis.ExpectZRangeWithScores("tenant-a:car:"+dirtyKeyName, 0, 0).SetVal([]redis.Z{})
+
+		_, errorStats := writeBehind.Stats()
+		assert.Nil(t, errorStats)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("namespaced copies leave the original as it is", func(t *testing.T) {
+		itemCache := ItemCache[Car](itemKeyFormat, logger, nil)
+		WriteBehind[Car](itemCache, nil, 10, logger, nil)
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			nil,
+			nil,
+		)
+
+		scopedCache := itemCache.WithNamespace("tenant-a")
+		scopedPagination := pagination.WithNamespace("tenant-a")
+
+		assert.Equal(t, itemKeyFormat, itemCache.itemKeyFormat)
+		assert.Equal(t, "car:"+dirtyKeyName, itemCache.dirtyKey)
+		assert.Equal(t, "tenant-a:car:"+dirtyKeyName, scopedCache.dirtyKey)
+		assert.Equal(t, "car:brands:%s:category:%s", pagination.paginationRedisFormat)
+		assert.Equal(t, "car:%s", pagination.itemCache.(*ItemCacheType[Car]).itemKeyFormat)
+		assert.Equal(t, "tenant-a:car:%s", scopedPagination.itemCache.(*ItemCacheType[Car]).itemKeyFormat)
+	})
+
+	t.Run("namespaces replace each other instead of stacking", func(t *testing.T) {
+		itemCache := ItemCache[Car](itemKeyFormat, logger, nil).WithNamespace("tenant-a").WithNamespace("tenant-b")
+		assert.Equal(t, "tenant-b:"+itemKeyFormat, itemCache.itemKeyFormat)
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			nil,
+			nil,
+		).WithNamespace("tenant-a").WithNamespace("tenant-b")
+		assert.Equal(t, "tenant-b:car:brands:%s:category:%s", pagination.paginationRedisFormat)
+		assert.Equal(t, "tenant-b:car:%s", pagination.itemCache.(*ItemCacheType[Car]).itemKeyFormat)
+	})
+
+	t.Run("repeating the namespace is a no-op", func(t *testing.T) {
+		itemCache := ItemCache[Car](itemKeyFormat, logger, nil).WithNamespace("tenant:a")
+		assert.Same(t, itemCache, itemCache.WithNamespace("tenant:a"))
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			nil,
+			nil,
+		).WithNamespace("tenant:a")
+		assert.Same(t, pagination, pagination.WithNamespaceFrom(ContextWithNamespace(context.TODO(), "tenant:a")))
+	})
+
+	t.Run("purge every key of the namespace", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectScan(0, `tenant-\*:*`, namespaceScanCount).SetVal([]string{"tenant-*:car:a", "tenant-*:car:b"}, 7)
+		mockRedis.ExpectUnlink("tenant-*:car:a", "tenant-*:car:b").SetVal(2)
+		mockRedis.ExpectScan(7, `tenant-\*:*`, namespaceScanCount).SetVal([]string{}, 0)
+		mockRedis.ExpectScan(0, `{tenant-\*:*`, namespaceScanCount).SetVal([]string{"{tenant-*:car:brands:a}:descby:createdat"}, 0)
+		mockRedis.ExpectUnlink("{tenant-*:car:brands:a}:descby:createdat").SetVal(1)
+
+		purged, errorPurge := PurgeNamespace(context.TODO(), redisDB, "tenant-*")
+		assert.Nil(t, errorPurge)
+		assert.Equal(t, int64(3), purged)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("refuse to purge without a namespace", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+
+		_, errorPurge := PurgeNamespace(context.TODO(), redisDB, "")
+		assert.Equal(t, INVALID_KEY_PARAMETERS, errorPurge.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("scan failure", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectScan(0, "tenant-a:*", namespaceScanCount).SetErr(redis.ErrClosed)
+
+		errorScan := ScanNamespace(context.TODO(), redisDB, "tenant-a", func(redis.UniversalClient, []string) error {
+			return nil
+		})
+		assert.Equal(t, REDIS_FATAL_ERROR, errorScan.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"log/slog"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/lefalya/commoncrud/interfaces"
 	"github.com/lefalya/commoncrud/types"
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
@@ -18,13 +22,20 @@ const (
 
 	ascendingTrailing  = ":ascby:"
 	descendingTrailing = ":descby:"
+
+	// idAttribute sorts lexicographically by the time-sortable UUID of items,
+	// see NewSortableItem.
+	idAttribute = "uuid"
 )
 
 type PaginationType[T interfaces.Item] struct {
+	entityName              string
 	logger                  *slog.Logger
 	redisClient             redis.UniversalClient
 	filter                  []string
+	filterFields            []int
 	itemCache               interfaces.ItemCache[T]
+	ownsItemCache           bool
 	itemKeyFormat           string
 	itemPerPage             int64
 	attribute               string
@@ -37,8 +48,20 @@ type PaginationType[T interfaces.Item] struct {
 	highestScoreKeyTrailing string
 	lowestScoreKeyTrailing  string
 	sortedSetKeyTrailing    string
+	seedGroup               *singleflight.Group
+	seedLockTTL             time.Duration
+	seedLockWait            time.Duration
+	facetSeeder             interfaces.FacetSeedFunc
+	maxSize                 int64
+	hashTag                 bool
+	namespace               string
+	softDelete              bool
+	eventStream             string
+	eventMaxLen             int64
 }
 
+// Pagination reads and writes items through itemCache, so decorated caches
+// and mocks can be plugged in; nil builds one keyed by entityName.
 func Pagination[T interfaces.Item](
 	entityName string,
 	attribute string,
@@ -48,11 +71,16 @@ func Pagination[T interfaces.Item](
 	suffix string,
 	logger *slog.Logger,
 	redisClient redis.UniversalClient,
+	itemCache interfaces.ItemCache[T],
 ) *PaginationType[T] {
-	itemCache := &ItemCacheType[T]{
-		logger:        logger,
-		redisClient:   redisClient,
-		itemKeyFormat: attribute + ":%s",
+	// items are keyed by entity so every pagination of it shares one copy
+	ownsItemCache := itemCache == nil
+	if ownsItemCache {
+		itemCache = &ItemCacheType[T]{
+			logger:        logger,
+			redisClient:   redisClient,
+			itemKeyFormat: entityName + ":%s",
+		}
 	}
 
 	var middleKey string
@@ -69,24 +97,39 @@ func Pagination[T interfaces.Item](
 	keyFormat = entityName + middleKey
 
 	pagination := &PaginationType[T]{
+		entityName:            entityName,
 		attribute:             attribute,
 		direction:             order,
 		paginationRedisFormat: keyFormat,
 		logger:                logger,
 		redisClient:           redisClient,
 		itemCache:             itemCache,
+		ownsItemCache:         ownsItemCache,
 		filter:                filterBy,
+		itemPerPage:           itemPerPage,
+		seedGroup:             new(singleflight.Group),
 	}
 
-	t := reflect.TypeOf((*T)(nil)).Elem()
+	// UpdateItem moves items between lists only when it can read every
+	// filter off the item
+	filterFields := fieldIndexes[T](filterBy)
+	pagination.filterFields = filterFields
+	for _, index := range filterFields {
+		if index < 0 {
+			pagination.filterFields = nil
+			break
+		}
+	}
+
+	t := structTypeOf[T]()
 
-	if pagination.attribute == "createdat" {
+	if pagination.byCreation() {
 		pagination.index = 0
 		if pagination.direction == ascending {
-			pagination.sortedSetKeyTrailing = ascendingTrailing + "createdat" + formattedSuffix
+			pagination.sortedSetKeyTrailing = ascendingTrailing + pagination.attribute + formattedSuffix
 			pagination.cardinalityKeyTrailing = pagination.sortedSetKeyTrailing + ":cardinality"
 		} else {
-			pagination.sortedSetKeyTrailing = descendingTrailing + "createdat" + formattedSuffix
+			pagination.sortedSetKeyTrailing = descendingTrailing + pagination.attribute + formattedSuffix
 		}
 	} else {
 		for i := 0; i < t.NumField(); i++ {
@@ -112,476 +155,890 @@ func Pagination[T interfaces.Item](
 }
 
 func (pg *PaginationType[T]) AddItem(item T, paginationParameters ...string) *types.PaginationError {
-	key := concatKey(pg.paginationRedisFormat, paginationParameters)
+	key, errorKey := pg.baseKey(paginationParameters)
+	if errorKey != nil {
+		return errorKey
+	}
 
-	totalItem := pg.redisClient.ZCard(
-		context.TODO(),
-		key+pg.sortedSetKeyTrailing,
-	)
-	if totalItem.Err() != nil {
+	errorHooks := beforeWrite(&item)
+	if errorHooks != nil {
+		return errorHooks
+	}
+
+	errorDeleted := pg.rejectDeleted(item)
+	if errorDeleted != nil {
+		return errorDeleted
+	}
+
+	totalItem, bookkeeping, errorRead := pg.readBookkeeping(key)
+	if errorRead != nil {
+		return errorRead
+	}
+
+	// like before bookkeeping was recovered, only items entering the sorted
+	// set are cached
+	_, addToSortedSet, _, errorAdmission := pg.admission(item, totalItem, bookkeeping)
+	if errorAdmission != nil {
+		return errorAdmission
+	}
+	if addToSortedSet {
+		errorSet := pg.itemCache.Set(item)
+		if errorSet != nil {
+			return &types.PaginationError{
+				Err:     errorSet.Err,
+				Details: errorSet.Details,
+				Message: "Failed to set item to Redis",
+			}
+		}
+	}
+
+	var added *redis.IntCmd
+	var errorPlan *types.PaginationError
+	_, errorWrite := pg.writePipelined([]string{key}, func(pipe redis.Pipeliner) error {
+		added, errorPlan = pg.planAdd(pipe, key, item, totalItem, bookkeeping, pg.events(EVENT_ADD, key, item))
+		if errorPlan != nil {
+			return errorPlan.Err
+		}
+		return nil
+	})
+	if errorPlan != nil {
+		return errorPlan
+	}
+	if errorWrite != nil {
 		return &types.PaginationError{
 			Err:     REDIS_FATAL_ERROR,
-			Details: totalItem.Err().Error(),
-			Message: "Failed to count total items on Redis",
+			Details: errorWrite.Error(),
+			Message: "Failed to add item to pagination set on Redis",
 		}
 	}
-	// only add item to sorted set, if the sorted set exists
-	if totalItem.Val() > 0 {
-		var score float64
-		addToSortedSet := false
-		// sort createdAt ascending
-		if pg.attribute == "createdat" && pg.direction == ascending {
-			cardinalityFromRedis := pg.redisClient.Get(context.TODO(), key+pg.cardinalityKeyTrailing)
-			if cardinalityFromRedis.Err() != nil {
-				if cardinalityFromRedis.Err() == redis.Nil {
-					// TODO: reingest cardinality but if reingestion failed then return redis fatal error
-				}
-				return &types.PaginationError{
-					Err:     REDIS_FATAL_ERROR,
-					Details: cardinalityFromRedis.Err().Error(),
-					Message: "Failed to get cardinality on Redis",
-				}
-			}
 
-			cardinality, errorParseInt := strconv.ParseInt(cardinalityFromRedis.Val(), 10, 64)
-			if errorParseInt != nil {
-				// TODO: reingest cardinality but if reingestion failed then return redis fatal error
-				return &types.PaginationError{
-					Err:     REDIS_FATAL_ERROR,
-					Details: cardinalityFromRedis.Err().Error(),
-					Message: "Failed to parse cardinality on Redis",
-				}
-			}
+	// an item already in the sorted set isn't counted twice
+	if added == nil || added.Val() > 0 {
+		errorCount := pg.countListed(key, paginationParameters, 1)
+		if errorCount != nil {
+			return errorCount
+		}
+	}
 
-			if totalItem.Val() == cardinality {
-				addToSortedSet = true
-				score = float64(item.GetCreatedAt().UnixMilli())
-			}
-		} else if pg.attribute == "createdat" && pg.direction == descending {
-			addToSortedSet = true
-			score = float64(item.GetCreatedAt().UnixMilli())
-
-			if totalItem.Val() >= pg.itemPerPage && totalItem.Val()%pg.itemPerPage != 0 {
-				deleteSettledKey := pg.redisClient.Del(context.TODO(), key+pg.settledKeyTrailing)
-				if deleteSettledKey.Err() != nil {
-					// TODO: remove sorted set
-					return &types.PaginationError{
-						Err:     REDIS_FATAL_ERROR,
-						Details: deleteSettledKey.Err().Error(),
-						Message: "Failed to delete settled key on Redis",
-					}
-				}
-			}
-		} else {
-			value := reflect.ValueOf(&item).Elem().Field(pg.index).Interface()
-			if value != nil {
-				switch v := value.(type) {
-				case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32:
-					score = float64(v.(int64))
-				case float64:
-					score = v
-				default:
-					score = float64(0)
-				}
-			} else {
-				return &types.PaginationError{
-					Err: FOUND_SORTING_BUT_NO_VALUE,
-				}
-			}
+	return pg.trimSortedSet(key)
+}
 
-			//var highestScore float64
-			//var lowestScore float64
-			var thresholdScore float64
-			var scoreKey string
-			var errorMessage string
-
-			if pg.direction == ascending {
-				scoreKey = key + pg.highestScoreKeyTrailing
-				errorMessage = "Failed to get highest score of custom attribute sorted set"
-			} else if pg.direction == descending {
-				scoreKey = key + pg.lowestScoreKeyTrailing
-				errorMessage = "Failed to get lowest score of custom attribute sorted set"
-			}
+// WithEvents publishes an "add", "update" or "remove" event carrying the
+// sorted set key to stream whenever item enters, changes in or leaves a
+// cached sorted set. The event is queued with the sorted set write itself,
+// so it is only published once that write is. maxLen caps the stream
+// approximately, 0 leaves it unbounded.
+func (pg *PaginationType[T]) WithEvents(stream string, maxLen int64) *PaginationType[T] {
+	pg.eventStream = stream
+	pg.eventMaxLen = maxLen
+	return pg
+}
 
-			errorGetThreshold := pg.redisClient.Get(context.TODO(), scoreKey)
-			if errorGetThreshold.Err() != nil {
-				if errorGetThreshold.Err() == redis.Nil {
-					// TODO remove sorted set
-				}
-				return &types.PaginationError{
-					Err:     REDIS_FATAL_ERROR,
-					Details: errorGetThreshold.Err().Error(),
-					Message: errorMessage,
-				}
-			}
+func (pg *PaginationType[T]) events(operation string, key string, item T) []streamEvent {
+	if pg.eventStream == "" {
+		return nil
+	}
 
-			thresholdScore, errorParseFloat := strconv.ParseFloat(errorGetThreshold.Val(), 64)
-			if errorParseFloat != nil {
-				// TODO: remove sorted set
-				return &types.PaginationError{
-					Err:     REDIS_FATAL_ERROR,
-					Details: errorParseFloat.Error(),
-					Message: "Failed to parse threshold score on Redis",
-				}
-			}
+	return []streamEvent{{
+		stream: pg.eventStream,
+		maxLen: pg.eventMaxLen,
+		event: types.ChangeEvent{
+			Operation:     operation,
+			Entity:        pg.entityName,
+			RandId:        item.GetRandId(),
+			PaginationKey: key + pg.sortedSetKeyTrailing,
+			Version:       item.GetUpdatedAt().UnixMilli(),
+		},
+	}}
+}
 
-			if pg.direction == ascending && score <= thresholdScore {
-				addToSortedSet = true
-			} else if pg.direction == descending && score >= thresholdScore {
-				addToSortedSet = true
-			}
+// byCreation tells whether the pagination follows creation order, by
+// createdat or by time-sortable id, rather than a custom attribute.
+func (pg *PaginationType[T]) byCreation() bool {
+	return pg.attribute == "createdat" || pg.attribute == idAttribute
+}
 
-			// will return 1 or 0 depends on settledKey existence.
-			if totalItem.Val() >= pg.itemPerPage && totalItem.Val()%pg.itemPerPage != 0 {
-				deleteSettledKey := pg.redisClient.Del(context.TODO(), key+pg.settledKeyTrailing)
-				if deleteSettledKey.Err() != nil {
-					// TODO: remove sorted set
-					return &types.PaginationError{
-						Err:     REDIS_FATAL_ERROR,
-						Details: deleteSettledKey.Err().Error(),
-						Message: "Failed to delete settled key on Redis",
-					}
-				}
-			}
+// creationScore is the score of item in a pagination by creation. Sorting by
+// id gives every member the same score so Redis orders them by member.
+func (pg *PaginationType[T]) creationScore(item T) float64 {
+	if pg.attribute == idAttribute {
+		return 0
+	}
+
+	return float64(item.GetCreatedAt().UnixMilli())
+}
+
+// Member returns the sorted set member of item: its randId, prefixed with
+// its UUID when sorting by id. References passed to Query and
+// PastCachedWindow are members.
+func (pg *PaginationType[T]) Member(item T) string {
+	if pg.attribute == idAttribute {
+		return item.GetUUID() + ":" + item.GetRandId()
+	}
+
+	return item.GetRandId()
+}
+
+// randIdOf returns the randId of a sorted set member.
+func (pg *PaginationType[T]) randIdOf(member string) string {
+	if pg.attribute == idAttribute {
+		return member[strings.LastIndexByte(member, ':')+1:]
+	}
+
+	return member
+}
+
+// scoreOf reads the sorting attribute of item as a sorted set score.
+func (pg *PaginationType[T]) scoreOf(item T) (float64, *types.PaginationError) {
+	fields, ok := structValueOf(item)
+	if !ok {
+		return 0, &types.PaginationError{
+			Err: FOUND_SORTING_BUT_NO_VALUE,
 		}
+	}
 
-		if addToSortedSet {
-			errorSet := pg.itemCache.Set(item)
-			if errorSet != nil {
-				return &types.PaginationError{
-					Err:     errorSet.Err,
-					Details: errorSet.Details,
-					Message: "Failed to set item to Redis",
-				}
+	value := fields.Field(pg.index)
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return 0, &types.PaginationError{
+				Err: FOUND_SORTING_BUT_NO_VALUE,
 			}
+		}
+		value = value.Elem()
+	}
 
-			sortedSetMember := redis.Z{
-				Score:  score,
-				Member: item.GetRandId(),
-			}
-			setSortedSet := pg.redisClient.ZAdd(
-				context.TODO(),
-				key+pg.sortedSetKeyTrailing,
-				sortedSetMember,
-			)
-			if setSortedSet.Err() != nil {
-				return &types.PaginationError{
-					Err:     REDIS_FATAL_ERROR,
-					Details: setSortedSet.Err().Error(),
-					Message: "Failed to add item to pagination set on Redis",
-				}
-			}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	default:
+		return 0, nil
+	}
+}
 
-			setExpire := pg.redisClient.Expire(
-				context.TODO(),
-				key+pg.sortedSetKeyTrailing,
-				SORTED_SET_TTL,
-			)
-			if setExpire.Err() != nil {
-				return &types.PaginationError{
-					Err:     REDIS_FATAL_ERROR,
-					Details: setExpire.Err().Error(),
-					Message: "Failed to extend pagination set expiration on Redis",
-				}
-			}
+// recomputeThreshold restores the highest (ascending) or lowest (descending)
+// score key from the boundary member of the sorted set. found is false when
+// the sorted set is empty.
+func (pg *PaginationType[T]) recomputeThreshold(key string) (float64, bool, *types.PaginationError) {
+	var scoreKey string
+	var boundary *redis.ZSliceCmd
+	if pg.direction == ascending {
+		scoreKey = key + pg.highestScoreKeyTrailing
+		boundary = pg.redisClient.ZRangeWithScores(context.TODO(), key+pg.sortedSetKeyTrailing, -1, -1)
+	} else {
+		scoreKey = key + pg.lowestScoreKeyTrailing
+		boundary = pg.redisClient.ZRangeWithScores(context.TODO(), key+pg.sortedSetKeyTrailing, 0, 0)
+	}
+
+	if boundary.Err() != nil {
+		return 0, false, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: boundary.Err().Error(),
+			Message: "Failed to get boundary member of sorted set",
+		}
+	}
+
+	if len(boundary.Val()) == 0 {
+		return 0, false, nil
+	}
+
+	threshold := boundary.Val()[0].Score
+	setThreshold := pg.redisClient.Set(context.TODO(), scoreKey, threshold, SORTED_SET_TTL)
+	if setThreshold.Err() != nil {
+		return 0, false, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: setThreshold.Err().Error(),
+			Message: "Failed to set threshold score on Redis",
+		}
+	}
+
+	return threshold, true, nil
+}
+
+// dropSortedSet removes the sorted set and its bookkeeping keys so the next
+// fetch reseeds them from the database.
+func (pg *PaginationType[T]) dropSortedSet(key string) *types.PaginationError {
+	errorSlot := pg.validateSlot(pg.componentKeys(key)...)
+	if errorSlot != nil {
+		return errorSlot
+	}
+
+	deleteKeys := pg.redisClient.Del(context.TODO(), pg.componentKeys(key)...)
+	if deleteKeys.Err() != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: deleteKeys.Err().Error(),
+			Message: "Failed to drop pagination set on Redis",
 		}
 	}
 
 	return nil
 }
 
+// UpdateItem updates item in the list of paginationParameters. When every
+// filterBy name is the bson or db tag of an item field, the previous state
+// of item is read from the item cache: if a filter attribute changed, item
+// is moved out of its previous list, see MoveItem.
 func (pg *PaginationType[T]) UpdateItem(item T, paginationParameters ...string) *types.PaginationError {
-	key := concatKey(pg.paginationRedisFormat, paginationParameters)
+	key, errorKey := pg.baseKey(paginationParameters)
+	if errorKey != nil {
+		return errorKey
+	}
+
+	errorHooks := beforeWrite(&item)
+	if errorHooks != nil {
+		return errorHooks
+	}
+
+	previousParameters, moved, errorPrevious := pg.previousParameters(item)
+	if errorPrevious != nil {
+		return errorPrevious
+	}
+	if moved {
+		return pg.MoveItem(item, previousParameters, paginationParameters...)
+	}
 
 	errorSet := pg.itemCache.Set(item)
 	if errorSet != nil {
 		return errorSet
 	}
 
-	if pg.attribute != "createdat" {
-		var score float64
-		// zrank if sorted set exists...
-		rank := pg.redisClient.ZRank(context.TODO(), key+pg.sortedSetKeyTrailing, item.GetRandId())
-		if rank.Err() != nil {
-			if rank.Err() == redis.Nil {
-				return nil
-			}
-			return &types.PaginationError{
-				Err:     REDIS_FATAL_ERROR,
-				Details: rank.Err().Error(),
-				Message: "Fatal error while getting member's rank from sorted set",
-			}
+	// by creation the score never changes, only the event is left to write
+	events := pg.events(EVENT_UPDATE, key, item)
+	if pg.byCreation() && len(events) == 0 {
+		return nil
+	}
+
+	// zrank if sorted set exists...
+	rank := pg.redisClient.ZRank(context.TODO(), key+pg.sortedSetKeyTrailing, pg.Member(item))
+	if rank.Err() != nil {
+		if rank.Err() == redis.Nil {
+			return nil
+		}
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: rank.Err().Error(),
+			Message: "Fatal error while getting member's rank from sorted set",
 		}
+	}
 
-		value := reflect.ValueOf(&item).Elem().Field(pg.index).Interface()
-		if value != nil {
-			switch v := value.(type) {
-			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32:
-				score = float64(v.(int64))
-			case float64:
-				score = v
-			default:
-				score = float64(0)
-			}
-		} else {
-			return &types.PaginationError{
-				Err: FOUND_SORTING_BUT_NO_VALUE,
-			}
+	var member redis.Z
+	if !pg.byCreation() {
+		score, errorScore := pg.scoreOf(item)
+		if errorScore != nil {
+			return errorScore
 		}
 
-		member := redis.Z{
+		member = redis.Z{
 			Score:  score,
-			Member: item.GetRandId(),
-		}
-		updateSortedSet := pg.redisClient.ZAdd(context.TODO(), key+pg.sortedSetKeyTrailing, member)
-		if updateSortedSet.Err() != nil {
-			return &types.PaginationError{
-				Err:     REDIS_FATAL_ERROR,
-				Details: updateSortedSet.Err().Error(),
-				Message: "Failed to update score on sorted set!",
-			}
+			Member: pg.Member(item),
 		}
+	}
 
-		updateSortedSetExpiration := pg.redisClient.Expire(
-			context.TODO(),
-			key+pg.sortedSetKeyTrailing,
-			SORTED_SET_TTL,
-		)
-		if updateSortedSetExpiration.Err() != nil {
-			return &types.PaginationError{
-				Err:     REDIS_FATAL_ERROR,
-				Details: updateSortedSetExpiration.Err().Error(),
-				Message: "Failed to extend sorted set expiration!",
-			}
+	errorWrite := writeWithEvents(pg.redisClient, false, events, func(pipe redis.Pipeliner) {
+		if !pg.byCreation() {
+			pipe.ZAdd(context.TODO(), key+pg.sortedSetKeyTrailing, member)
+		}
+	})
+	if errorWrite != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorWrite.Error(),
+			Message: "Failed to update score on sorted set!",
 		}
 	}
 
-	return nil
+	if pg.byCreation() {
+		return nil
+	}
+	return pg.extendSortedSet(key)
 }
 
-func (pg *PaginationType[T]) RemoveItem(pagKeyParams []string, item T) *types.PaginationError {
-	key := concatKey(pg.paginationRedisFormat, pagKeyParams)
+func (pg *PaginationType[T]) RemoveItem(item T, paginationParameters ...string) *types.PaginationError {
+	key, errorKey := pg.baseKey(paginationParameters)
+	if errorKey != nil {
+		return errorKey
+	}
 
 	errorDelete := pg.itemCache.Del(item)
 	if errorDelete != nil {
 		return errorDelete
 	}
 
-	itemRank := pg.redisClient.ZRank(
-		context.TODO(),
-		key,
-		item.GetRandId(),
-	)
-	if itemRank.Err() != nil {
-		if itemRank.Err() == redis.Nil {
-			// will skip member removal from sorted set
-			return nil
-		}
+	return pg.unlist(key, item, paginationParameters)
+}
+
+// unlist takes item, already dropped from the item cache, out of the
+// pagination set at key and its counters, publishing the "remove" event
+// along with the removal.
+func (pg *PaginationType[T]) unlist(key string, item T, paginationParameters []string) *types.PaginationError {
+	var read membership
+	_, errorRead := pg.redisClient.Pipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		read = pg.readMembership(pipe, key, item)
+		return nil
+	})
+	if errorRead != nil && errorRead != redis.Nil {
 		return &types.PaginationError{
 			Err:     REDIS_FATAL_ERROR,
-			Details: itemRank.Err().Error(),
-			Message: "Failed to count total items on Redis",
+			Details: errorRead.Error(),
+			Message: "Failed to get member's rank from sorted set",
 		}
 	}
 
-	// only remove item from sorted set, if the sorted set exists
-	removeItemFromSortedSet := pg.redisClient.ZRem(context.TODO(), key+pg.sortedSetKeyTrailing, item.GetRandId())
-	if removeItemFromSortedSet.Err() != nil {
+	// will skip member removal from sorted set
+	if !read.listed() {
+		return pg.countRemoval(key, paginationParameters, item, read, nil)
+	}
+
+	var removed *redis.IntCmd
+	errorRemove := writeWithEvents(pg.redisClient, false, pg.events(EVENT_REMOVE, key, item), func(pipe redis.Pipeliner) {
+		removed = pipe.ZRem(context.TODO(), key+pg.sortedSetKeyTrailing, pg.Member(item))
+	})
+	if errorRemove != nil {
 		return &types.PaginationError{
 			Err:     REDIS_FATAL_ERROR,
-			Details: removeItemFromSortedSet.Err().Error(),
+			Details: errorRemove.Error(),
 			Message: "Failed to remove item from pagination set on Redis",
 		}
 	}
 
-	// if attribute is not createdat then re-set the highest & lowest key
-	if pg.attribute == "createdat" {
+	errorCount := pg.countRemoval(key, paginationParameters, item, read, removed)
+	if errorCount != nil {
+		return errorCount
+	}
+	// removed meanwhile, the boundary was settled by then
+	if removed.Val() == 0 {
+		return nil
+	}
 
-	} else {
-		var score float64
-		value := reflect.ValueOf(&item).Elem().Field(pg.index).Interface()
-		if value != nil {
-			switch v := value.(type) {
-			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32:
-				score = float64(v.(int64))
-			case float64:
-				score = v
-			default:
-				score = float64(0)
-			}
-		} else {
-			return &types.PaginationError{
-				Err: FOUND_SORTING_BUT_NO_VALUE,
-			}
+	// if attribute is not createdat then re-set the highest & lowest key
+	if !pg.byCreation() {
+		score, errorScore := pg.scoreOf(item)
+		if errorScore != nil {
+			return errorScore
 		}
 
-		var thersholdKey string
+		var thresholdKey string
 		if pg.direction == ascending {
-			thersholdKey = key + pg.highestScoreKeyTrailing
+			thresholdKey = key + pg.highestScoreKeyTrailing
 		} else if pg.direction == descending {
-			thersholdKey = key + pg.lowestScoreKeyTrailing
+			thresholdKey = key + pg.lowestScoreKeyTrailing
 		}
 
-		thresholdFromCache := pg.redisClient.Get(context.TODO(), thersholdKey)
-		if thresholdFromCache.Err() != nil {
-			// TODO redis error, will decide what to do in future
+		thresholdFromCache := pg.redisClient.Get(context.TODO(), thresholdKey)
+		if thresholdFromCache.Err() != nil && thresholdFromCache.Err() != redis.Nil {
+			return &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: thresholdFromCache.Err().Error(),
+				Message: "Failed to get threshold score on Redis",
+			}
 		}
 
 		threshold, errorParseFloat := strconv.ParseFloat(thresholdFromCache.Val(), 64)
-		if errorParseFloat != nil {
-			// TODO will decide what to do in future
+		// the removed item was the boundary, take the score of the new one
+		if thresholdFromCache.Err() == redis.Nil || errorParseFloat != nil || threshold == score {
+			return pg.settleBoundary(key)
 		}
+	}
+
+	return nil
+}
 
-		if threshold == score {
-			// TODO ambil item te
+// previousParameters reads the pagination parameters of the cached state of
+// item, and whether its filter fields differ from those of item. Fields are
+// compared rather than keys, so parameters the caller formats differently
+// from the fields don't pass for a move.
+func (pg *PaginationType[T]) previousParameters(item T) ([]string, bool, *types.PaginationError) {
+	if pg.filterFields == nil {
+		return nil, false, nil
+	}
+
+	previous, errorGet := pg.itemCache.Get(item.GetRandId())
+	if errorGet != nil {
+		if errorGet.Err == KEY_NOT_FOUND {
+			return nil, false, nil
 		}
+		return nil, false, errorGet
 	}
 
-	return nil
+	previousParameters := fieldParameters(previous, pg.filterFields)
+	return previousParameters, !slices.Equal(previousParameters, fieldParameters(item, pg.filterFields)), nil
 }
 
-/*
-func (pg *PaginationType[T]) TotalItemOnCache(pagKeyParams []string) *types.PaginationError {
-	key := concatKey(pg.pagKeyFormat, pagKeyParams)
+// MoveItem updates item and moves it from the pagination set of
+// previousParameters to the one of paginationParameters, in a single
+// transaction. Use it when the item's filter attributes changed.
+//
+// With WithHashTag each list lives on its own cluster slot and MULTI can't
+// span both, so the move goes out as a plain pipeline instead: a failure
+// midway may leave item in both lists or in neither, until they're reseeded.
+func (pg *PaginationType[T]) MoveItem(item T, previousParameters []string, paginationParameters ...string) *types.PaginationError {
+	previousKey, errorKey := pg.baseKey(previousParameters)
+	if errorKey != nil {
+		return errorKey
+	}
+	key, errorKey := pg.baseKey(paginationParameters)
+	if errorKey != nil {
+		return errorKey
+	}
+	if previousKey == key {
+		return pg.UpdateItem(item, paginationParameters...)
+	}
 
-	var sortedSetKey string
-	if pg.sorting != nil && pg.sorting.direction == ascending {
-		sortedSetKey = key + ascendingTrailing + pg.sorting.attribute
-	} else if pg.sorting != nil && pg.sorting.direction == descending {
-		sortedSetKey = key + descendingTrailing + pg.sorting.attribute
-	} else {
-		sortedSetKey = key + descendingTrailing + "createdat"
+	errorHooks := beforeWrite(&item)
+	if errorHooks != nil {
+		return errorHooks
 	}
 
-	totalItem := pg.redisClient.ZCard(
-		context.TODO(),
-		sortedSetKey,
-	)
+	errorSet := pg.itemCache.Set(item)
+	if errorSet != nil {
+		return errorSet
+	}
 
-	if totalItem.Err() != nil {
+	var totalItem *redis.IntCmd
+	var bookkeeping, previousBookkeeping *redis.StringCmd
+	var previousMembership membership
+	_, errorRead := pg.redisClient.Pipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		totalItem = pipe.ZCard(context.TODO(), key+pg.sortedSetKeyTrailing)
+		if bookkeepingKey := pg.bookkeepingKey(key); bookkeepingKey != "" {
+			bookkeeping = pipe.Get(context.TODO(), bookkeepingKey)
+			previousBookkeeping = pipe.Get(context.TODO(), pg.bookkeepingKey(previousKey))
+		}
+		previousMembership = pg.readMembership(pipe, previousKey, item)
+		return nil
+	})
+	if errorRead != nil && errorRead != redis.Nil {
 		return &types.PaginationError{
 			Err:     REDIS_FATAL_ERROR,
-			Details: totalItem.Err().Error(),
-			Message: "Failed to count total items on Redis",
+			Details: errorRead.Error(),
+			Message: "Failed to read pagination sets bookkeeping on Redis",
+		}
+	}
+
+	bookkeeping, errorRecover := pg.recoverBookkeeping(key, totalItem.Val(), bookkeeping)
+	if errorRecover != nil {
+		return errorRecover
+	}
+
+	var boundary bool
+	var removed, added *redis.IntCmd
+	var errorPlan *types.PaginationError
+	_, errorWrite := pg.writePipelined([]string{previousKey, key}, func(pipe redis.Pipeliner) error {
+		removed, boundary, errorPlan = pg.planRemove(pipe, previousKey, item, previousMembership, previousBookkeeping, pg.events(EVENT_REMOVE, previousKey, item))
+		if errorPlan != nil {
+			return errorPlan.Err
+		}
+
+		added, errorPlan = pg.planAdd(pipe, key, item, totalItem.Val(), bookkeeping, pg.events(EVENT_ADD, key, item))
+		if errorPlan != nil {
+			return errorPlan.Err
+		}
+		return nil
+	})
+	if errorPlan != nil {
+		return errorPlan
+	}
+	if errorWrite != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorWrite.Error(),
+			Message: "Failed to move item between pagination sets on Redis",
 		}
 	}
 
+	errorCount := pg.countRemoval(previousKey, previousParameters, item, previousMembership, removed)
+	if errorCount != nil {
+		return errorCount
+	}
+	if added == nil || added.Val() > 0 {
+		errorCount = pg.countListed(key, paginationParameters, 1)
+		if errorCount != nil {
+			return errorCount
+		}
+	}
+
+	errorTrim := pg.trimSortedSet(key)
+	if errorTrim != nil {
+		return errorTrim
+	}
+
+	if boundary {
+		return pg.settleBoundary(previousKey)
+	}
+
 	return nil
 }
 
-func (pg *PaginationType[T]) FetchOne(randId string) (*T, *types.PaginationError) {
-	item, errorGet := pg.itemCache.Get(randId)
+// readBookkeeping reads, in one pipeline, the size of the sorted set of key
+// and its bookkeeping value, recovered with recoverBookkeeping.
+func (pg *PaginationType[T]) readBookkeeping(key string) (int64, *redis.StringCmd, *types.PaginationError) {
+	var totalItem *redis.IntCmd
+	var bookkeeping *redis.StringCmd
+	_, errorRead := pg.redisClient.Pipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		totalItem = pipe.ZCard(context.TODO(), key+pg.sortedSetKeyTrailing)
+		if bookkeepingKey := pg.bookkeepingKey(key); bookkeepingKey != "" {
+			bookkeeping = pipe.Get(context.TODO(), bookkeepingKey)
+		}
+		return nil
+	})
+	if errorRead != nil && errorRead != redis.Nil {
+		return 0, nil, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorRead.Error(),
+			Message: "Failed to read pagination set bookkeeping on Redis",
+		}
+	}
 
-	if errorGet != nil {
-		return nil, errorGet
+	bookkeeping, errorRecover := pg.recoverBookkeeping(key, totalItem.Val(), bookkeeping)
+	if errorRecover != nil {
+		return 0, nil, errorRecover
 	}
 
-	return &item, nil
+	return totalItem.Val(), bookkeeping, nil
+}
+
+// recoverBookkeeping restores a missing threshold score from the boundary
+// member of a non-empty sorted set. A missing cardinality can't be recovered
+// from Redis alone and is left for planAdd to drop the sorted set.
+func (pg *PaginationType[T]) recoverBookkeeping(key string, totalItem int64, bookkeeping *redis.StringCmd) (*redis.StringCmd, *types.PaginationError) {
+	if totalItem == 0 || bookkeeping == nil || pg.cardinalityKeyTrailing != "" {
+		return bookkeeping, nil
+	}
+
+	_, errorParseFloat := strconv.ParseFloat(bookkeeping.Val(), 64)
+	if bookkeeping.Err() == nil && errorParseFloat == nil {
+		return bookkeeping, nil
+	}
+
+	threshold, found, errorRecompute := pg.recomputeThreshold(key)
+	if errorRecompute != nil {
+		return nil, errorRecompute
+	}
+	if !found {
+		return bookkeeping, nil
+	}
+
+	return redis.NewStringResult(strconv.FormatFloat(threshold, 'f', -1, 64), nil), nil
+}
+
+// admission decides whether item enters the sorted set of key, and with
+// which score, given the size of the sorted set and its bookkeeping value
+// read beforehand, see readBookkeeping. intact is false when the bookkeeping
+// is missing or malformed, leaving the sorted set to be reseeded.
+func (pg *PaginationType[T]) admission(item T, totalItem int64, bookkeeping *redis.StringCmd) (float64, bool, bool, *types.PaginationError) {
+	// only add item to sorted set, if the sorted set exists
+	if totalItem == 0 {
+		return 0, false, true, nil
+	}
+
+	if pg.byCreation() && pg.direction == ascending {
+		cardinality, errorParseInt := strconv.ParseInt(bookkeeping.Val(), 10, 64)
+		if bookkeeping.Err() != nil || errorParseInt != nil {
+			return 0, false, false, nil
+		}
+
+		return pg.creationScore(item), totalItem == cardinality, true, nil
+	}
+
+	if pg.byCreation() {
+		return pg.creationScore(item), true, true, nil
+	}
+
+	score, errorScore := pg.scoreOf(item)
+	if errorScore != nil {
+		return 0, false, false, errorScore
+	}
+
+	threshold, errorParseFloat := strconv.ParseFloat(bookkeeping.Val(), 64)
+	if bookkeeping.Err() != nil || errorParseFloat != nil {
+		return 0, false, false, nil
+	}
+
+	if pg.direction == ascending {
+		return score, score <= threshold, true, nil
+	}
+	return score, score >= threshold, true, nil
 }
 
+// planAdd queues on pipe the writes adding item to the list of key, given
+// the size of the sorted set and its bookkeeping value read beforehand, see
+// readBookkeeping. Missing bookkeeping drops the sorted set so it gets
+// reseeded. events are queued only when item enters the sorted set, whose
+// ZADD is returned, nil when item stays past the cached window: the caller
+// counts item in with countListed unless the ZADD reports it was listed.
+func (pg *PaginationType[T]) planAdd(
+	pipe redis.Pipeliner,
+	key string,
+	item T,
+	totalItem int64,
+	bookkeeping *redis.StringCmd,
+	events []streamEvent,
+) (*redis.IntCmd, *types.PaginationError) {
+	if totalItem == 0 {
+		return nil, nil
+	}
+
+	score, addToSortedSet, intact, errorAdmission := pg.admission(item, totalItem, bookkeeping)
+	if errorAdmission != nil {
+		return nil, errorAdmission
+	}
+	if !intact {
+		pipe.Del(context.TODO(), pg.componentKeys(key)...)
+		return nil, nil
+	}
+
+	pastWindow := totalItem >= pg.itemPerPage && totalItem%pg.itemPerPage != 0
+	if pg.byCreation() && pg.direction == ascending {
+		if !addToSortedSet {
+			pipe.Del(context.TODO(), key+pg.settledKeyTrailing)
+		}
+	} else if pastWindow {
+		pipe.Del(context.TODO(), key+pg.settledKeyTrailing)
+	}
+
+	if !addToSortedSet {
+		return nil, nil
+	}
+
+	added := pipe.ZAdd(context.TODO(), key+pg.sortedSetKeyTrailing, redis.Z{
+		Score:  score,
+		Member: pg.Member(item),
+	})
+	for _, component := range pg.componentKeys(key) {
+		pipe.PExpire(context.TODO(), component, SORTED_SET_TTL)
+	}
+	for _, event := range events {
+		event.add(pipe)
+	}
+
+	return added, nil
+}
+
+// planRemove queues on pipe the removal of item from the sorted set of key,
+// followed by events, given its membership and bookkeeping value read
+// beforehand; nothing is queued when item isn't in the sorted set. The ZREM
+// is returned, for countRemoval. boundary reports that item was the threshold
+// item, to be fixed with settleBoundary once pipe is executed.
+func (pg *PaginationType[T]) planRemove(
+	pipe redis.Pipeliner,
+	key string,
+	item T,
+	m membership,
+	bookkeeping *redis.StringCmd,
+	events []streamEvent,
+) (*redis.IntCmd, bool, *types.PaginationError) {
+	if !m.listed() {
+		return nil, false, nil
+	}
+
+	removed := pipe.ZRem(context.TODO(), key+pg.sortedSetKeyTrailing, pg.Member(item))
+	for _, event := range events {
+		event.add(pipe)
+	}
+
+	if bookkeeping == nil || bookkeeping.Err() != nil || pg.cardinalityKeyTrailing != "" {
+		return removed, false, nil
+	}
+
+	score, errorScore := pg.scoreOf(item)
+	if errorScore != nil {
+		return removed, false, errorScore
+	}
+
+	threshold, errorParseFloat := strconv.ParseFloat(bookkeeping.Val(), 64)
+	return removed, errorParseFloat != nil || threshold == score, nil
+}
+
+// settleBoundary recomputes the threshold of key after its boundary item was
+// removed, dropping the sorted set when nothing is left in it.
+func (pg *PaginationType[T]) settleBoundary(key string) *types.PaginationError {
+	_, found, errorRecompute := pg.recomputeThreshold(key)
+	if errorRecompute != nil {
+		return errorRecompute
+	}
+	if !found {
+		return pg.dropSortedSet(key)
+	}
+
+	return nil
+}
+
+// FetchLinked returns the page following references, the sorted set members
+// of the last items of the previous page, see Member, or the first page
+// without references.
+// Past the cached window of a capped sorted set, see PastCachedWindow, the
+// page is loaded through seeder instead, see SeedLinked; with a nil seeder
+// only what's cached is returned. Soft deleted items are left out, see
+// WithSoftDelete.
 func (pg *PaginationType[T]) FetchLinked(
-	pagKeyParams []string,
 	references []string,
-	itemPerPage int64,
-	processor interfaces.PaginationProcessor[T]) ([]T, *types.PaginationError) {
-	var items []T
-	var start int64
-	var stop int64
+	processor interfaces.PaginationProcessor[T],
+	seeder interfaces.LinkedSeedFunc[T],
+	paginationParameters ...string,
+) ([]T, *types.PaginationError) {
+	if len(references) > MAXIMUM_AMOUNT_REFERENCES {
+		return nil, &types.PaginationError{
+			Err:     TOO_MUCH_REFERENCES,
+			Message: "Too much references!",
+		}
+	}
 
-	key := concatKey(pg.pagKeyFormat, pagKeyParams)
-	totalReferences := len(references)
+	key, errorKey := pg.baseKey(paginationParameters)
+	if errorKey != nil {
+		return nil, errorKey
+	}
 
-	if totalReferences > 0 {
-		if totalReferences > MAXIMUM_AMOUNT_REFERENCES {
+	start, pastWindow, errorWindow := pg.cachedWindow(key, references)
+	if errorWindow != nil {
+		return nil, errorWindow
+	}
+
+	var fetched []T
+	if pastWindow && seeder != nil {
+		lastItem, errorLast := pg.lastReference(references)
+		if errorLast != nil {
+			return nil, errorLast
+		}
+
+		seeded, errorSeed := pg.SeedLinked(lastItem, seeder, paginationParameters...)
+		if errorSeed != nil {
+			return nil, errorSeed
+		}
+		for _, item := range seeded {
+			if !deletedAt(item).IsZero() {
+				continue
+			}
+			fetched = append(fetched, item)
+		}
+	} else {
+		if start == -1 {
 			return nil, &types.PaginationError{
-				Err:     TOO_MUCH_REFERENCES,
-				Message: "Too much references!",
+				Err:     NO_VALID_REFERENCES,
+				Message: "No references found from pagination set on Redis",
 			}
 		}
 
-		for i := len(references) - 1; i >= 0; i-- {
-			var rank *redis.IntCmd
-			if pg.sorting != nil && pg.sorting.direction == ascending {
-				sortedSetKey := key + ascendingTrailing + pg.sorting.attribute
-				rank = pg.redisClient.ZRank(context.TODO(), sortedSetKey, references[i])
-			} else if pg.sorting != nil && pg.sorting.direction == descending {
-				sortedSetKey := key + descendingTrailing + pg.sorting.attribute
-				rank = pg.redisClient.ZRevRank(context.TODO(), sortedSetKey, references[i])
-			} else {
-				sortedSetKey := key + descendingTrailing + "createdat"
-				rank = pg.redisClient.ZRevRank(context.TODO(), sortedSetKey, references[i])
+		var members *redis.StringSliceCmd
+		if pg.direction == ascending {
+			members = pg.redisClient.ZRange(context.TODO(), key+pg.sortedSetKeyTrailing, start, start+pg.itemPerPage-1)
+		} else {
+			members = pg.redisClient.ZRevRange(context.TODO(), key+pg.sortedSetKeyTrailing, start, start+pg.itemPerPage-1)
+		}
+		if members.Err() != nil {
+			return nil, &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: members.Err().Error(),
+				Message: "Failed to get items from pagination set on Redis",
 			}
+		}
 
-			if rank.Err() != nil {
-				if rank.Err() == redis.Nil {
+		for _, member := range members.Val() {
+			item, errorGetItem := pg.itemCache.Get(pg.randIdOf(member))
+			if errorGetItem != nil {
+				if errorGetItem.Err == KEY_NOT_FOUND {
 					continue
 				}
-
-				return nil, &types.PaginationError{
-					Err:     REDIS_FATAL_ERROR,
-					Details: rank.Err().Error(),
-					Message: "Failed to get reference's index from pagination set on Redis",
-				}
+				return nil, errorGetItem
+			}
+			if !deletedAt(item).IsZero() {
+				continue
 			}
 
-			start = rank.Val() + 1
-			break
+			fetched = append(fetched, item)
 		}
+	}
 
-		if start == 0 {
-			return nil, &types.PaginationError{
-				Err:     NO_VALID_REFERENCES,
-				Message: "No references found from pagination set on Redis",
-			}
+	fetched, errorLive := pg.live(fetched)
+	if errorLive != nil {
+		return nil, errorLive
+	}
+
+	var items []T
+	for _, item := range fetched {
+		if processor != nil {
+			processor(item, &items)
+		} else {
+			items = append(items, item)
 		}
 	}
 
-	stop = start + itemPerPage - 1
+	return items, nil
+}
 
-	var members *redis.StringSliceCmd
+// lastReference reads from the item cache the last of references, sorted set
+// members, still cached, or returns the zero T without references.
+func (pg *PaginationType[T]) lastReference(references []string) (T, *types.PaginationError) {
+	var lastItem T
+	for i := len(references) - 1; i >= 0; i-- {
+		item, errorGetItem := pg.itemCache.Get(pg.randIdOf(references[i]))
+		if errorGetItem == nil {
+			return item, nil
+		}
+		if errorGetItem.Err != KEY_NOT_FOUND {
+			return lastItem, errorGetItem
+		}
+	}
+
+	if len(references) > 0 {
+		return lastItem, &types.PaginationError{
+			Err:     NO_VALID_REFERENCES,
+			Message: "No references found from item cache on Redis",
+		}
+	}
+
+	return lastItem, nil
+}
+
+// SeedLinked loads through seeder the page of itemPerPage items following
+// lastItem, or the first page when lastItem is the zero T.
+func (pg *PaginationType[T]) SeedLinked(
+	lastItem T,
+	seeder interfaces.LinkedSeedFunc[T],
+	paginationParameters ...string,
+) ([]T, *types.PaginationError) {
+	return seeder(lastItem, pg.itemPerPage, paginationParameters...)
+}
+
+/*
+func (pg *PaginationType[T]) TotalItemOnCache(pagKeyParams []string) *types.PaginationError {
+	key := concatKey(pg.pagKeyFormat, pagKeyParams)
+
+	var sortedSetKey string
 	if pg.sorting != nil && pg.sorting.direction == ascending {
-		sortedSetKey := key + ascendingTrailing + pg.sorting.attribute
-		members = pg.redisClient.ZRange(context.TODO(), sortedSetKey, start, stop)
+		sortedSetKey = key + ascendingTrailing + pg.sorting.attribute
 	} else if pg.sorting != nil && pg.sorting.direction == descending {
-		sortedSetKey := key + descendingTrailing + pg.sorting.attribute
-		members = pg.redisClient.ZRevRange(context.TODO(), sortedSetKey, start, stop)
+		sortedSetKey = key + descendingTrailing + pg.sorting.attribute
 	} else {
-		sortedSetKey := key + descendingTrailing + "createdat"
-		members = pg.redisClient.ZRevRange(context.TODO(), sortedSetKey, start, stop)
+		sortedSetKey = key + descendingTrailing + "createdat"
 	}
 
-	if members.Err() != nil {
-		return nil, &types.PaginationError{
+	totalItem := pg.redisClient.ZCard(
+		context.TODO(),
+		sortedSetKey,
+	)
+
+	if totalItem.Err() != nil {
+		return &types.PaginationError{
 			Err:     REDIS_FATAL_ERROR,
-			Details: members.Err().Error(),
-			Message: "Failed to get items from pagination set on Redis",
+			Details: totalItem.Err().Error(),
+			Message: "Failed to count total items on Redis",
 		}
 	}
 
-	if len(members.Val()) > 0 {
-		pg.redisClient.Expire(context.TODO(), key, SORTED_SET_TTL)
+	return nil
+}
 
-		for _, member := range members.Val() {
-			item, errorGetItem := pg.itemCache.Get(member)
-			if errorGetItem != nil && errorGetItem.Err == REDIS_FATAL_ERROR {
-				return nil, &types.PaginationError{
-					Err:     errorGetItem.Err,
-					Details: errorGetItem.Details,
-					Message: "Failed to get item details from Redis",
-				}
-			} else if errorGetItem != nil && errorGetItem.Err == KEY_NOT_FOUND {
-				continue
-			}
+func (pg *PaginationType[T]) FetchOne(randId string) (*T, *types.PaginationError) {
+	item, errorGet := pg.itemCache.Get(randId)
 
-			if processor != nil {
-				processor(item, &items)
-			} else {
-				items = append(items, item)
-			}
-		}
+	if errorGet != nil {
+		return nil, errorGet
 	}
 
-	return items, nil
+	return &item, nil
 }
 
 func (pg *PaginationType[T]) FetchAll(pagKeyParams []string, processor interfaces.PaginationProcessor[T]) ([]T, *types.PaginationError) {
@@ -665,91 +1122,6 @@ func (pg *PaginationType[T]) SeedOne(randId string) (*T, *types.PaginationError)
 	return &result, nil
 }
 
-func (pg *PaginationType[T]) SeedLinked(
-	paginationKeyParameters []string,
-	reference T,
-	itemPerPage int64,
-	processor interfaces.SeedProcessor[T],
-) ([]T, *types.PaginationError) {
-	var result []T
-	var filter bson.D
-
-	if pg.mongo != nil {
-		if !reflect.ValueOf(reference).IsZero() {
-			inMongoItem, ok := any(reference).(interfaces.MongoItem)
-			if !ok {
-				// return lastItem must be in MongoItem
-				return nil, &types.PaginationError{
-					Err:     LASTITEM_MUST_MONGOITEM,
-					Message: "Using MongoDB as database but the reference is not in MongoItem",
-				}
-			}
-
-			filter = bson.D{
-				{
-					Key: "$and",
-					Value: append(
-						pg.mongo.GetPaginationFilter(),
-						bson.A{
-							bson.D{
-								{
-									Key: "_id",
-									Value: bson.D{
-										{
-											Key:   "$lt",
-											Value: inMongoItem.GetObjectId(),
-										},
-									},
-								},
-							},
-						}...,
-					),
-				},
-			}
-		} else {
-			if pg.mongo != nil {
-				filter = bson.D{
-					{Key: "$and", Value: pg.mongo.GetPaginationFilter()},
-				}
-			}
-		}
-
-		findOptions := options.Find()
-		if pg.sorting != nil && pg.sorting.direction == ascending {
-			findOptions.SetSort(bson.D{{"_id", 1}})
-		} else {
-			findOptions.SetSort(bson.D{{"_id", -1}})
-		}
-
-		findOptions.SetLimit(itemPerPage)
-
-		items, errorFindItems := pg.mongo.FindMany(
-			filter,
-			findOptions,
-			pg,
-			paginationKeyParameters,
-			processor,
-		)
-
-		if errorFindItems != nil {
-			return nil, &types.PaginationError{
-				Err:     errorFindItems.Err,
-				Details: errorFindItems.Details,
-				Message: "MongoDB fatal error while retrieving items",
-			}
-		}
-
-		result = items
-	} else {
-		return nil, &types.PaginationError{
-			Err:     NO_DATABASE_CONFIGURED,
-			Message: "No database configured",
-		}
-	}
-
-	return result, nil
-}
-
 func (pg *PaginationType[T]) SeedAll(
 	paginationKeyParameters []string,
 	processor interfaces.SeedProcessor[T],
@@ -29,7 +29,7 @@ var (
 	itemKeyFormatAircraft        = "aircraft:%s"
 	paginationKeyFormatAircraft  = "aircraft:brands:%s:type:%s"
 	paginationParametersAircraft = []string{"Boeing", "Narrow Body"}
-	keyAircraft                  = concatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
+	keyAircraft                  = mustConcatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
 )
 
 type Engine struct {
@@ -131,7 +131,7 @@ func TestAddItemIntegration(t *testing.T) {
 			Score:  float64(time.Now().Unix()),
 			Member: RandId(),
 		}
-		key := concatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
+		key := mustConcatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
 		zadd := redisClient.ZAdd(
 			context.TODO(),
 			key+descendingTrailing+"createdat",
@@ -175,7 +175,7 @@ func TestAddItemIntegration(t *testing.T) {
 			Score:  float64(time.Now().UnixMilli()),
 			Member: RandId(),
 		}
-		key := concatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
+		key := mustConcatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
 		zadd := redisClient.ZAdd(
 			context.TODO(),
 			key+descendingTrailing+"createdat",
@@ -233,7 +233,7 @@ func TestAddItemIntegration(t *testing.T) {
 			Score:  float64(10),
 			Member: RandId(),
 		}
-		key := concatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
+		key := mustConcatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
 		zadd := redisClient.ZAdd(
 			context.TODO(),
 			key+descendingTrailing+"ranking",
@@ -310,7 +310,7 @@ func TestAddItemIntegration(t *testing.T) {
 			Score:  float64(10),
 			Member: RandId(),
 		}
-		key := concatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
+		key := mustConcatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
 		zadd := redisClient.ZAdd(
 			context.TODO(),
 			key+ascendingTrailing+"createdat",
@@ -389,7 +389,7 @@ func TestAddItemIntegration(t *testing.T) {
 			Score:  float64(10),
 			Member: RandId(),
 		}
-		key := concatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
+		key := mustConcatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
 		zadd := redisClient.ZAdd(
 			context.TODO(),
 			key+ascendingTrailing+"ranking",
@@ -472,7 +472,7 @@ func TestRemoveItemIntegration(t *testing.T) {
 			Score:  float64(time.Now().UnixMilli()),
 			Member: RandId(),
 		}
-		key := concatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
+		key := mustConcatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
 		zadd := redisClient.ZAdd(
 			context.TODO(),
 			key+descendingTrailing+"createdat",
@@ -523,7 +523,7 @@ func TestRemoveItemIntegration(t *testing.T) {
 			Score:  float64(time.Now().UnixMilli()),
 			Member: RandId(),
 		}
-		key := concatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
+		key := mustConcatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
 		zadd := redisClient.ZAdd(
 			context.TODO(),
 			key+descendingTrailing+"createdat",
@@ -569,7 +569,7 @@ func TestRemoveItemIntegration(t *testing.T) {
 			Score:  float64(time.Now().UnixMilli()),
 			Member: RandId(),
 		}
-		key := concatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
+		key := mustConcatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
 		zadd := redisClient.ZAdd(
 			context.TODO(),
 			key+descendingTrailing+"ranking",
@@ -620,7 +620,7 @@ func TestRemoveItemIntegration(t *testing.T) {
 			Score:  float64(time.Now().UnixMilli()),
 			Member: RandId(),
 		}
-		key := concatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
+		key := mustConcatKey(paginationKeyFormatAircraft, paginationParametersAircraft)
 		zadd := redisClient.ZAdd(
 			context.TODO(),
 			key+ascendingTrailing+"createdat",
@@ -3,10 +3,12 @@ package commoncrud
 import (
 	"github.com/go-redis/redismock/v9"
 	"github.com/golang/mock/gomock"
+	"github.com/lefalya/commoncrud/interfaces"
 	mock_interfaces "github.com/lefalya/commoncrud/mocks"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 var (
@@ -36,9 +38,50 @@ var (
 	paginationKeyFormat  = "car:brands:%s:category:%s"
 	paginationParameters = []string{"Volkswagen", "SUV"}
 
-	key = concatKey(paginationKeyFormat, paginationParameters)
+	key = mustConcatKey(paginationKeyFormat, paginationParameters)
 )
 
+func mustConcatKey(keyFormat string, parameters []string) string {
+	key, errorKey := concatKey(keyFormat, parameters)
+	if errorKey != nil {
+		panic(errorKey.Details)
+	}
+	return key
+}
+
+func mustSortedSetKey[T interfaces.Item](pagination *PaginationType[T], paginationParameters ...string) string {
+	key, errorKey := pagination.SortedSetKey(paginationParameters...)
+	if errorKey != nil {
+		panic(errorKey.Details)
+	}
+	return key
+}
+
+// expectExtendSortedSet expects the PEXPIRE of every component key queued
+// after adding to a sorted set.
+func expectExtendSortedSet(mockRedis redismock.ClientMock, keys ...string) {
+	for _, component := range keys {
+		mockRedis.ExpectPExpire(component, SORTED_SET_TTL).SetVal(true)
+	}
+}
+
+// expectMembership expects the membership reads of member in sortedSetKey:
+// the boundary read in direction, then the rank of member, missing when
+// negative.
+func expectMembership(mockRedis redismock.ClientMock, sortedSetKey string, member string, rank int64, direction string, boundary ...redis.Z) {
+	if direction == ascending {
+		mockRedis.ExpectZRangeWithScores(sortedSetKey, -1, -1).SetVal(boundary)
+	} else {
+		mockRedis.ExpectZRangeWithScores(sortedSetKey, 0, 0).SetVal(boundary)
+	}
+
+	if rank < 0 {
+		mockRedis.ExpectZRank(sortedSetKey, member).RedisNil()
+	} else {
+		mockRedis.ExpectZRank(sortedSetKey, member).SetVal(rank)
+	}
+}
+
 type Seater struct {
 	Material  string
 	Occupancy int64
@@ -75,6 +118,7 @@ func TestInitPagiantion(t *testing.T) {
 			itemPerPage,
 			"",
 			nil,
+			nil,
 			nil)
 
 		assert.NotNil(t, pagination)
@@ -85,6 +129,39 @@ func TestInitPagiantion(t *testing.T) {
 		assert.Equal(t, "car:brands:%s:category:%s", pagination.paginationRedisFormat)
 	})
 
+	t.Run("use the injected item cache", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(key + descendingTrailing + "createdat").SetVal(0)
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			itemCache,
+		)
+
+		assert.Nil(t, pagination.AddItem(car, brand, category))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("paginations of an entity share the item keyspace", func(t *testing.T) {
+		byCreatedAt := Pagination[Car]("car", "createdat", descending, []string{"brands"}, itemPerPage, "", nil, nil, nil)
+		byRanking := Pagination[Car]("car", "ranking", ascending, []string{"category"}, itemPerPage, "", nil, nil, nil)
+
+		assert.Equal(t, itemKeyFormat, byCreatedAt.itemCache.(*ItemCacheType[Car]).itemKeyFormat)
+		assert.Equal(t, itemKeyFormat, byRanking.itemCache.(*ItemCacheType[Car]).itemKeyFormat)
+	})
+
 	t.Run("(createdAt ascending) init pagination", func(t *testing.T) {
 		pagination := Pagination[Car](
 			"car",
@@ -94,6 +171,7 @@ func TestInitPagiantion(t *testing.T) {
 			itemPerPage,
 			"oldesttonewest",
 			nil,
+			nil,
 			nil)
 
 		assert.NotNil(t, pagination)
@@ -114,6 +192,7 @@ func TestInitPagiantion(t *testing.T) {
 			itemPerPage,
 			"",
 			nil,
+			nil,
 			nil)
 
 		assert.NotNil(t, pagination)
@@ -134,6 +213,7 @@ func TestInitPagiantion(t *testing.T) {
 			itemPerPage,
 			"descendingrank",
 			nil,
+			nil,
 			nil)
 
 		assert.Equal(t, descending, pagination.direction)
@@ -154,6 +234,7 @@ func TestInitPagiantion(t *testing.T) {
 			itemPerPage,
 			"lowesttohighest",
 			nil,
+			nil,
 			nil)
 
 		assert.Equal(t, ascending, pagination.direction)
@@ -166,6 +247,53 @@ func TestInitPagiantion(t *testing.T) {
 	})
 }
 
+func TestConcatKey(t *testing.T) {
+	t.Run("lowercase and dash parameters", func(t *testing.T) {
+		key, errorKey := concatKey(paginationKeyFormat, []string{"Volkswagen", "Sport Utility"})
+		assert.Nil(t, errorKey)
+		assert.Equal(t, "car:brands:volkswagen:category:sport-utility", key)
+	})
+
+	t.Run("escape separators so parameters can't add segments", func(t *testing.T) {
+		injected, errorKey := concatKey(paginationKeyFormat, []string{"a:category:b", "c"})
+		assert.Nil(t, errorKey)
+		assert.Equal(t, "car:brands:a%3Acategory%3Ab:category:c", injected)
+
+		escaped, errorKey := concatKey(paginationKeyFormat, []string{"100%3A", "{tag}"})
+		assert.Nil(t, errorKey)
+		assert.Equal(t, "car:brands:100%253a:category:%7Btag%7D", escaped)
+	})
+
+	t.Run("reject parameter count not matching the format", func(t *testing.T) {
+		key, errorKey := concatKey(paginationKeyFormat, []string{"Volkswagen"})
+		assert.Equal(t, "", key)
+		assert.Equal(t, INVALID_KEY_PARAMETERS, errorKey.Err)
+	})
+
+	t.Run("add item with missing parameters", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+		pagination.itemCache = mock_interfaces.NewMockItemCache[Car](ctrl)
+
+		errorAddItem := pagination.AddItem(car, brand)
+		assert.Equal(t, INVALID_KEY_PARAMETERS, errorAddItem.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
+
 func TestAddItem(t *testing.T) {
 	// createdAt sorting
 	t.Run("(createdat descending) successfully add item", func(t *testing.T) {
@@ -186,8 +314,13 @@ func TestAddItem(t *testing.T) {
 			Score:  float64(carImpl.GetCreatedAt().UnixMilli()),
 			Member: carImpl.GetRandId(),
 		}
+		mockRedis.ExpectTxPipeline()
 		mockRedis.ExpectZAdd(key+descendingTrailing+"createdat", expectedZMember).SetVal(1)
-		mockRedis.ExpectExpire(key+descendingTrailing+"createdat", SORTED_SET_TTL).SetVal(true)
+		expectExtendSortedSet(mockRedis,
+			key+descendingTrailing+"createdat",
+			key+descendingTrailing+"createdat"+":settled",
+		)
+		mockRedis.ExpectTxPipelineExec()
 
 		pagination := Pagination[Car](
 			"car",
@@ -198,6 +331,7 @@ func TestAddItem(t *testing.T) {
 			"",
 			logger,
 			redisDB,
+			nil,
 		)
 		pagination.itemCache = itemCache
 		errorAddItem := pagination.AddItem(carImpl, brand, category)
@@ -221,8 +355,15 @@ func TestAddItem(t *testing.T) {
 			Score:  float64(carImpl.GetCreatedAt().UnixMilli()),
 			Member: carImpl.GetRandId(),
 		}
+		mockRedis.ExpectTxPipeline()
 		mockRedis.ExpectZAdd(key+ascendingTrailing+"createdat", expectedZMember).SetVal(1)
-		mockRedis.ExpectExpire(key+ascendingTrailing+"createdat", SORTED_SET_TTL).SetVal(true)
+		expectExtendSortedSet(mockRedis,
+			key+ascendingTrailing+"createdat",
+			key+ascendingTrailing+"createdat"+":settled",
+			key+ascendingTrailing+"createdat"+":cardinality",
+		)
+		mockRedis.ExpectTxPipelineExec()
+		mockRedis.ExpectEvalSha(incrementIfExists.Hash(), []string{key + ascendingTrailing + "createdat" + ":cardinality"}, int64(1)).SetVal(int64(4))
 
 		pagination := Pagination[Car](
 			"car",
@@ -233,10 +374,12 @@ func TestAddItem(t *testing.T) {
 			"",
 			logger,
 			redisDB,
+			nil,
 		)
 		pagination.itemCache = itemCache
 		errorAddItem := pagination.AddItem(carImpl, brand, category)
 		assert.Nil(t, errorAddItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
 	})
 	t.Run("(createdAt ascending) totalItem lower than cardinality", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -247,12 +390,14 @@ func TestAddItem(t *testing.T) {
 
 		// itemcache expectations
 		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
-		itemCache.EXPECT().Set(carImpl).Return(nil)
 
 		redisDB, mockRedis := redismock.NewClientMock()
 		mockRedis.ExpectZCard(key + ascendingTrailing + "createdat").SetVal(3)
 		mockRedis.ExpectGet(key + ascendingTrailing + "createdat" + ":cardinality").SetVal("2")
+		mockRedis.ExpectTxPipeline()
 		mockRedis.ExpectDel(key + ascendingTrailing + "createdat" + ":settled").SetVal(1)
+		mockRedis.ExpectTxPipelineExec()
+		mockRedis.ExpectEvalSha(incrementIfExists.Hash(), []string{key + ascendingTrailing + "createdat" + ":cardinality"}, int64(1)).SetVal(int64(3))
 
 		pagination := Pagination[Car](
 			"car",
@@ -263,11 +408,84 @@ func TestAddItem(t *testing.T) {
 			"",
 			logger,
 			redisDB,
+			nil,
 		)
 		pagination.itemCache = itemCache
 
 		errorAddItem := pagination.AddItem(carImpl, brand, category)
 		assert.Nil(t, errorAddItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("(createdAt ascending) item already listed isn't counted twice", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Set(car).Return(nil)
+
+		sortedSetKey := key + ascendingTrailing + "createdat"
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(sortedSetKey).SetVal(3)
+		mockRedis.ExpectGet(sortedSetKey + ":cardinality").SetVal("3")
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZAdd(sortedSetKey, redis.Z{
+			Score:  float64(car.GetCreatedAt().UnixMilli()),
+			Member: car.GetRandId(),
+		}).SetVal(0)
+		expectExtendSortedSet(mockRedis, sortedSetKey, sortedSetKey+":settled", sortedSetKey+":cardinality")
+		mockRedis.ExpectTxPipelineExec()
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			ascending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+		pagination.itemCache = itemCache
+
+		errorAddItem := pagination.AddItem(car, brand, category)
+		assert.Nil(t, errorAddItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("(createdAt ascending) missing cardinality drops sorted set", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(key + ascendingTrailing + "createdat").SetVal(3)
+		mockRedis.ExpectGet(key + ascendingTrailing + "createdat" + ":cardinality").RedisNil()
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectDel(
+			key+ascendingTrailing+"createdat",
+			key+ascendingTrailing+"createdat"+":settled",
+			key+ascendingTrailing+"createdat"+":cardinality",
+		).SetVal(2)
+		mockRedis.ExpectTxPipelineExec()
+		mockRedis.ExpectEvalSha(incrementIfExists.Hash(), []string{key + ascendingTrailing + "createdat" + ":cardinality"}, int64(1)).RedisNil()
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			ascending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+		pagination.itemCache = itemCache
+
+		errorAddItem := pagination.AddItem(car, brand, category)
+		assert.Nil(t, errorAddItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
 	})
 	// custom sorting
 	t.Run("(custom ascending) successfully add item", func(t *testing.T) {
@@ -288,8 +506,14 @@ func TestAddItem(t *testing.T) {
 			Score:  float64(carImpl.Ranking),
 			Member: carImpl.GetRandId(),
 		}
+		mockRedis.ExpectTxPipeline()
 		mockRedis.ExpectZAdd(key+ascendingTrailing+"ranking", expectedZMember).SetVal(1)
-		mockRedis.ExpectExpire(key+ascendingTrailing+"ranking", SORTED_SET_TTL).SetVal(true)
+		expectExtendSortedSet(mockRedis,
+			key+ascendingTrailing+"ranking",
+			key+ascendingTrailing+"ranking"+":settled",
+			key+ascendingTrailing+"ranking"+":highestscore",
+		)
+		mockRedis.ExpectTxPipelineExec()
 
 		pagination := Pagination[Car](
 			"car",
@@ -300,6 +524,7 @@ func TestAddItem(t *testing.T) {
 			"",
 			logger,
 			redisDB,
+			nil,
 		)
 		pagination.itemCache = itemCache
 
@@ -324,8 +549,14 @@ func TestAddItem(t *testing.T) {
 			Score:  float64(carImpl.Ranking),
 			Member: carImpl.GetRandId(),
 		}
+		mockRedis.ExpectTxPipeline()
 		mockRedis.ExpectZAdd(key+descendingTrailing+"ranking", expectedZMember).SetVal(1)
-		mockRedis.ExpectExpire(key+descendingTrailing+"ranking", SORTED_SET_TTL).SetVal(true)
+		expectExtendSortedSet(mockRedis,
+			key+descendingTrailing+"ranking",
+			key+descendingTrailing+"ranking"+":settled",
+			key+descendingTrailing+"ranking"+":lowestscore",
+		)
+		mockRedis.ExpectTxPipelineExec()
 
 		pagination := Pagination[Car](
 			"car",
@@ -336,18 +567,65 @@ func TestAddItem(t *testing.T) {
 			"",
 			logger,
 			redisDB,
+			nil,
 		)
 		pagination.itemCache = itemCache
 		errorAddItem := pagination.AddItem(carImpl, brand, category)
 		assert.Nil(t, errorAddItem)
 	})
+	t.Run("(custom descending) missing threshold is recomputed from sorted set", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		carImpl := car
+		carImpl.Ranking = 89
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Set(carImpl).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(key + descendingTrailing + "ranking").SetVal(3)
+		mockRedis.ExpectGet(key + descendingTrailing + "ranking" + ":lowestscore").RedisNil()
+		mockRedis.ExpectZRangeWithScores(key+descendingTrailing+"ranking", 0, 0).SetVal([]redis.Z{
+			{Score: 50, Member: "lowestrandid"},
+		})
+		mockRedis.ExpectSet(key+descendingTrailing+"ranking"+":lowestscore", float64(50), SORTED_SET_TTL).SetVal("OK")
+		expectedZMember := redis.Z{
+			Score:  float64(carImpl.Ranking),
+			Member: carImpl.GetRandId(),
+		}
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZAdd(key+descendingTrailing+"ranking", expectedZMember).SetVal(1)
+		expectExtendSortedSet(mockRedis,
+			key+descendingTrailing+"ranking",
+			key+descendingTrailing+"ranking"+":settled",
+			key+descendingTrailing+"ranking"+":lowestscore",
+		)
+		mockRedis.ExpectTxPipelineExec()
+
+		pagination := Pagination[Car](
+			"car",
+			"ranking",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+		pagination.itemCache = itemCache
+
+		errorAddItem := pagination.AddItem(carImpl, brand, category)
+		assert.Nil(t, errorAddItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
 	t.Run("(custom descending) nil attribute value", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		// itemcache expectations
 		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
-		itemCache.EXPECT().Set(car).Return(nil)
 
 		redisDB, mockRedis := redismock.NewClientMock()
 		mockRedis.ExpectZCard(key + descendingTrailing + "ranking").SetVal(3)
@@ -362,6 +640,7 @@ func TestAddItem(t *testing.T) {
 			"",
 			logger,
 			redisDB,
+			nil,
 		)
 		pagination.itemCache = itemCache
 		errorAddItem := pagination.AddItem(car, brand, category)
@@ -383,8 +662,14 @@ func TestAddItem(t *testing.T) {
 			Score:  float64(0),
 			Member: car.GetRandId(),
 		}
+		mockRedis.ExpectTxPipeline()
 		mockRedis.ExpectZAdd(key+ascendingTrailing+"ranking", expectedZMember).SetVal(1)
-		mockRedis.ExpectExpire(key+ascendingTrailing+"ranking", SORTED_SET_TTL).SetVal(true)
+		expectExtendSortedSet(mockRedis,
+			key+ascendingTrailing+"ranking",
+			key+ascendingTrailing+"ranking"+":settled",
+			key+ascendingTrailing+"ranking"+":highestscore",
+		)
+		mockRedis.ExpectTxPipelineExec()
 
 		pagination := Pagination[Car](
 			"car",
@@ -395,6 +680,7 @@ func TestAddItem(t *testing.T) {
 			"",
 			logger,
 			redisDB,
+			nil,
 		)
 		pagination.itemCache = itemCache
 		errorAddItem := pagination.AddItem(car, brand, category)
@@ -419,6 +705,7 @@ func TestUpdateItem(t *testing.T) {
 			"",
 			logger,
 			nil,
+			nil,
 		)
 		pagination.itemCache = itemCache
 
@@ -441,6 +728,7 @@ func TestUpdateItem(t *testing.T) {
 			"",
 			logger,
 			nil,
+			nil,
 		)
 		pagination.itemCache = itemCache
 
@@ -464,7 +752,11 @@ func TestUpdateItem(t *testing.T) {
 			Member: carImpl.GetRandId(),
 		}
 		mockRedis.ExpectZAdd(key+ascendingTrailing+"ranking", expectedZMember).SetVal(1)
-		mockRedis.ExpectExpire(key+ascendingTrailing+"ranking", SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectEvalSha(expireTogether.Hash(), []string{
+			key + ascendingTrailing + "ranking",
+			key + ascendingTrailing + "ranking" + ":settled",
+			key + ascendingTrailing + "ranking" + ":highestscore",
+		}, SORTED_SET_TTL.Milliseconds()).SetVal(int64(3))
 
 		pagination := Pagination[Car](
 			"car",
@@ -475,6 +767,7 @@ func TestUpdateItem(t *testing.T) {
 			"",
 			logger,
 			redisDB,
+			nil,
 		)
 		pagination.itemCache = itemCache
 
@@ -498,7 +791,11 @@ func TestUpdateItem(t *testing.T) {
 			Member: carImpl.GetRandId(),
 		}
 		mockRedis.ExpectZAdd(key+descendingTrailing+"ranking", expectedZMember).SetVal(1)
-		mockRedis.ExpectExpire(key+descendingTrailing+"ranking", SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectEvalSha(expireTogether.Hash(), []string{
+			key + descendingTrailing + "ranking",
+			key + descendingTrailing + "ranking" + ":settled",
+			key + descendingTrailing + "ranking" + ":lowestscore",
+		}, SORTED_SET_TTL.Milliseconds()).SetVal(int64(3))
 
 		pagination := Pagination[Car](
 			"car",
@@ -509,6 +806,7 @@ func TestUpdateItem(t *testing.T) {
 			"",
 			logger,
 			redisDB,
+			nil,
 		)
 		pagination.itemCache = itemCache
 
@@ -536,12 +834,439 @@ func TestUpdateItem(t *testing.T) {
 			"",
 			logger,
 			redisDB,
+			nil,
 		)
 		pagination.itemCache = itemCache
 
 		errorUpdateItem := pagination.UpdateItem(carImpl, brand, category)
 		assert.Nil(t, errorUpdateItem)
 	})
+	t.Run("(createdat ascending) move item when a filter attribute changed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		previous := car
+		previous.Category = "Sedan"
+		previousKey := mustConcatKey("car:brand:%s:category:%s", []string{brand, "Sedan"}) + ascendingTrailing + "createdat"
+		newKey := mustConcatKey("car:brand:%s:category:%s", []string{brand, category}) + ascendingTrailing + "createdat"
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Get(car.GetRandId()).Return(previous, nil)
+		itemCache.EXPECT().Set(car).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(newKey).SetVal(3)
+		mockRedis.ExpectGet(newKey + ":cardinality").SetVal("3")
+		mockRedis.ExpectGet(previousKey + ":cardinality").SetVal("8")
+		expectMembership(mockRedis, previousKey, car.GetRandId(), 2, ascending, redis.Z{
+			Score:  float64(car.GetCreatedAt().Add(time.Hour).UnixMilli()),
+			Member: "latest",
+		})
+
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZRem(previousKey, car.GetRandId()).SetVal(1)
+		mockRedis.ExpectZAdd(newKey, redis.Z{
+			Score:  float64(car.GetCreatedAt().UnixMilli()),
+			Member: car.GetRandId(),
+		}).SetVal(1)
+		expectExtendSortedSet(mockRedis, newKey, newKey+":settled", newKey+":cardinality")
+		mockRedis.ExpectTxPipelineExec()
+		mockRedis.ExpectEvalSha(incrementIfExists.Hash(), []string{previousKey + ":cardinality"}, int64(-1)).SetVal(int64(7))
+		mockRedis.ExpectEvalSha(incrementIfExists.Hash(), []string{newKey + ":cardinality"}, int64(1)).SetVal(int64(4))
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			ascending,
+			[]string{"brand", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			itemCache,
+		)
+
+		errorUpdateItem := pagination.UpdateItem(car, brand, category)
+		assert.Nil(t, errorUpdateItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("(createdat ascending) keep item in its list when no filter attribute changed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Get(car.GetRandId()).Return(car, nil)
+		itemCache.EXPECT().Set(car).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			ascending,
+			[]string{"brand", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			itemCache,
+		)
+
+		errorUpdateItem := pagination.UpdateItem(car, brand, category)
+		assert.Nil(t, errorUpdateItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("(createdat ascending) parameters formatted apart from the fields aren't a move", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Get(car.GetRandId()).Return(car, nil)
+		itemCache.EXPECT().Set(car).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			ascending,
+			[]string{"brand", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			itemCache,
+		)
+
+		errorUpdateItem := pagination.UpdateItem(car, "VOLKSWAGEN", "suv")
+		assert.Nil(t, errorUpdateItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
+
+func TestSortById(t *testing.T) {
+	sortableCar := NewSortableItem(Car{Brand: brand, Category: category}, ULID)
+	sortedSetKey := key + ascendingTrailing + "uuid"
+	member := sortableCar.GetUUID() + ":" + sortableCar.GetRandId()
+
+	newPagination := func(redisDB *redis.Client, itemCache *mock_interfaces.MockItemCache[Car]) *PaginationType[Car] {
+		return Pagination[Car](
+			"car",
+			"uuid",
+			ascending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			itemCache,
+		)
+	}
+
+	t.Run("init pagination", func(t *testing.T) {
+		pagination := newPagination(nil, nil)
+		assert.Equal(t, ascendingTrailing+"uuid", pagination.sortedSetKeyTrailing)
+		assert.Equal(t, ascendingTrailing+"uuid:cardinality", pagination.cardinalityKeyTrailing)
+		assert.Equal(t, member, pagination.Member(sortableCar))
+		assert.Equal(t, sortableCar.GetRandId(), pagination.randIdOf(member))
+	})
+
+	t.Run("add item with equal scores ordered by id", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Set(sortableCar).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(sortedSetKey).SetVal(3)
+		mockRedis.ExpectGet(sortedSetKey + ":cardinality").SetVal("3")
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZAdd(sortedSetKey, redis.Z{
+			Score:  0,
+			Member: member,
+		}).SetVal(1)
+		expectExtendSortedSet(mockRedis, sortedSetKey, sortedSetKey+":settled", sortedSetKey+":cardinality")
+		mockRedis.ExpectTxPipelineExec()
+		mockRedis.ExpectEvalSha(incrementIfExists.Hash(), []string{sortedSetKey + ":cardinality"}, int64(1)).SetVal(int64(4))
+
+		errorAddItem := newPagination(redisDB, itemCache).AddItem(sortableCar, brand, category)
+		assert.Nil(t, errorAddItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("remove item by member", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Del(sortableCar).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		expectMembership(mockRedis, sortedSetKey, member, 1, ascending, redis.Z{Score: 0, Member: "zzz"})
+		mockRedis.ExpectZRem(sortedSetKey, member).SetVal(1)
+		mockRedis.ExpectEvalSha(incrementIfExists.Hash(), []string{sortedSetKey + ":cardinality"}, int64(-1)).SetVal(int64(2))
+
+		errorRemoveItem := newPagination(redisDB, itemCache).RemoveItem(sortableCar, brand, category)
+		assert.Nil(t, errorRemoveItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
+
+func TestMoveItem(t *testing.T) {
+	t.Run("(createdat ascending) move item to another category", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		previousKey := mustConcatKey(paginationKeyFormat, []string{brand, "Sedan"}) + ascendingTrailing + "createdat"
+		newKey := key + ascendingTrailing + "createdat"
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Set(car).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZCard(newKey).SetVal(3)
+		mockRedis.ExpectGet(newKey + ":cardinality").SetVal("3")
+		mockRedis.ExpectGet(previousKey + ":cardinality").SetVal("8")
+		expectMembership(mockRedis, previousKey, car.GetRandId(), 2, ascending, redis.Z{
+			Score:  float64(car.GetCreatedAt().Add(time.Hour).UnixMilli()),
+			Member: "latest",
+		})
+
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZRem(previousKey, car.GetRandId()).SetVal(1)
+		mockRedis.ExpectZAdd(newKey, redis.Z{
+			Score:  float64(car.GetCreatedAt().UnixMilli()),
+			Member: car.GetRandId(),
+		}).SetVal(1)
+		mockRedis.ExpectPExpire(newKey, SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectPExpire(newKey+":settled", SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectPExpire(newKey+":cardinality", SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectTxPipelineExec()
+		mockRedis.ExpectEvalSha(incrementIfExists.Hash(), []string{previousKey + ":cardinality"}, int64(-1)).SetVal(int64(7))
+		mockRedis.ExpectEvalSha(incrementIfExists.Hash(), []string{newKey + ":cardinality"}, int64(1)).SetVal(int64(4))
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			ascending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+		pagination.itemCache = itemCache
+
+		errorMoveItem := pagination.MoveItem(car, []string{brand, "Sedan"}, brand, category)
+		assert.Nil(t, errorMoveItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
+
+func TestRemoveItem(t *testing.T) {
+	t.Run("(createdat descending) successfully remove item", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Del(car).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		expectMembership(mockRedis, key+descendingTrailing+"createdat", car.GetRandId(), 2, descending, redis.Z{
+			Score:  float64(car.GetCreatedAt().Add(-time.Hour).UnixMilli()),
+			Member: "oldest",
+		})
+		mockRedis.ExpectZRem(key+descendingTrailing+"createdat", car.GetRandId()).SetVal(1)
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+		pagination.itemCache = itemCache
+
+		errorRemoveItem := pagination.RemoveItem(car, brand, category)
+		assert.Nil(t, errorRemoveItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("item not in sorted set", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Del(car).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		expectMembership(mockRedis, key+descendingTrailing+"createdat", car.GetRandId(), -1, descending)
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+		pagination.itemCache = itemCache
+
+		errorRemoveItem := pagination.RemoveItem(car, brand, category)
+		assert.Nil(t, errorRemoveItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("(createdat ascending) item past the cached window is counted out", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Del(car).Return(nil)
+
+		sortedSetKey := key + ascendingTrailing + "createdat"
+		redisDB, mockRedis := redismock.NewClientMock()
+		expectMembership(mockRedis, sortedSetKey, car.GetRandId(), -1, ascending, redis.Z{
+			Score:  float64(car.GetCreatedAt().Add(-time.Hour).UnixMilli()),
+			Member: "latestcached",
+		})
+		mockRedis.ExpectEvalSha(incrementIfExists.Hash(), []string{sortedSetKey + ":cardinality"}, int64(-1)).SetVal(int64(7))
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			ascending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+		pagination.itemCache = itemCache
+
+		errorRemoveItem := pagination.RemoveItem(car, brand, category)
+		assert.Nil(t, errorRemoveItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("(createdat ascending) item already removed isn't counted out twice", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Del(car).Return(nil)
+
+		sortedSetKey := key + ascendingTrailing + "createdat"
+		redisDB, mockRedis := redismock.NewClientMock()
+		expectMembership(mockRedis, sortedSetKey, car.GetRandId(), -1, ascending, redis.Z{
+			Score:  float64(car.GetCreatedAt().Add(time.Hour).UnixMilli()),
+			Member: "latestcached",
+		})
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			ascending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+		pagination.itemCache = itemCache
+
+		errorRemoveItem := pagination.RemoveItem(car, brand, category)
+		assert.Nil(t, errorRemoveItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("(custom ascending) removing the boundary item recomputes highest score", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		carImpl := car
+		carImpl.Ranking = 10
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Del(carImpl).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		expectMembership(mockRedis, key+ascendingTrailing+"ranking", carImpl.GetRandId(), 2, ascending, redis.Z{
+			Score:  10,
+			Member: carImpl.GetRandId(),
+		})
+		mockRedis.ExpectZRem(key+ascendingTrailing+"ranking", carImpl.GetRandId()).SetVal(1)
+		mockRedis.ExpectGet(key + ascendingTrailing + "ranking" + ":highestscore").SetVal("10")
+		mockRedis.ExpectZRangeWithScores(key+ascendingTrailing+"ranking", -1, -1).SetVal([]redis.Z{
+			{Score: 8, Member: "previousrandid"},
+		})
+		mockRedis.ExpectSet(key+ascendingTrailing+"ranking"+":highestscore", float64(8), SORTED_SET_TTL).SetVal("OK")
+
+		pagination := Pagination[Car](
+			"car",
+			"ranking",
+			ascending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+		pagination.itemCache = itemCache
+
+		errorRemoveItem := pagination.RemoveItem(carImpl, brand, category)
+		assert.Nil(t, errorRemoveItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("(custom descending) removing the last item drops the sorted set", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		carImpl := car
+		carImpl.Ranking = 50
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Del(carImpl).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		expectMembership(mockRedis, key+descendingTrailing+"ranking", carImpl.GetRandId(), 0, descending, redis.Z{
+			Score:  50,
+			Member: carImpl.GetRandId(),
+		})
+		mockRedis.ExpectZRem(key+descendingTrailing+"ranking", carImpl.GetRandId()).SetVal(1)
+		mockRedis.ExpectGet(key + descendingTrailing + "ranking" + ":lowestscore").SetVal("50")
+		mockRedis.ExpectZRangeWithScores(key+descendingTrailing+"ranking", 0, 0).SetVal([]redis.Z{})
+		mockRedis.ExpectDel(
+			key+descendingTrailing+"ranking",
+			key+descendingTrailing+"ranking"+":settled",
+			key+descendingTrailing+"ranking"+":lowestscore",
+		).SetVal(1)
+
+		pagination := Pagination[Car](
+			"car",
+			"ranking",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+		pagination.itemCache = itemCache
+
+		errorRemoveItem := pagination.RemoveItem(carImpl, brand, category)
+		assert.Nil(t, errorRemoveItem)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
 }
 
 /*
@@ -0,0 +1,254 @@
+package commoncrud
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/lefalya/commoncrud/interfaces"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	SET_OPERATION_INTERSECT = "intersect"
+	SET_OPERATION_UNION     = "union"
+	SET_OPERATION_DIFF      = "diff"
+
+	AGGREGATE_SUM = "sum"
+	AGGREGATE_MIN = "min"
+	AGGREGATE_MAX = "max"
+
+	queryKeyInfix = ":query:"
+)
+
+// SortedSetKey returns the full sorted set key for the given pagination
+// parameters, to be composed with other sets in a SetQuery.
+func (pg *PaginationType[T]) SortedSetKey(paginationParameters ...string) (string, *types.PaginationError) {
+	key, errorKey := pg.baseKey(paginationParameters)
+	if errorKey != nil {
+		return "", errorKey
+	}
+
+	return key + pg.sortedSetKeyTrailing, nil
+}
+
+// Query stores the result of the set operation in a short-lived sorted set
+// and returns one page of it, ordered in this pagination's direction. Like
+// FetchLinked, references are the members of the previous page; the page
+// starts after the last one still present in the result set. Identical
+// queries share the result set until it expires.
+func (pg *PaginationType[T]) Query(
+	query types.SetQuery,
+	references []string,
+	processor interfaces.PaginationProcessor[T],
+) ([]T, *types.PaginationError) {
+	resultKey, errorStore := pg.storeQuery(query)
+	if errorStore != nil {
+		return nil, errorStore
+	}
+
+	var start int64
+	totalReferences := len(references)
+	if totalReferences > 0 {
+		if totalReferences > MAXIMUM_AMOUNT_REFERENCES {
+			return nil, &types.PaginationError{
+				Err:     TOO_MUCH_REFERENCES,
+				Message: "Too much references!",
+			}
+		}
+
+		for i := totalReferences - 1; i >= 0; i-- {
+			var rank *redis.IntCmd
+			if pg.direction == ascending {
+				rank = pg.redisClient.ZRank(context.TODO(), resultKey, references[i])
+			} else {
+				rank = pg.redisClient.ZRevRank(context.TODO(), resultKey, references[i])
+			}
+
+			if rank.Err() != nil {
+				if rank.Err() == redis.Nil {
+					continue
+				}
+
+				return nil, &types.PaginationError{
+					Err:     REDIS_FATAL_ERROR,
+					Details: rank.Err().Error(),
+					Message: "Failed to get reference's index from query result on Redis",
+				}
+			}
+
+			start = rank.Val() + 1
+			break
+		}
+
+		if start == 0 {
+			return nil, &types.PaginationError{
+				Err:     NO_VALID_REFERENCES,
+				Message: "No references found from query result on Redis",
+			}
+		}
+	}
+
+	stop := start + pg.itemPerPage - 1
+
+	var members *redis.StringSliceCmd
+	if pg.direction == ascending {
+		members = pg.redisClient.ZRange(context.TODO(), resultKey, start, stop)
+	} else {
+		members = pg.redisClient.ZRevRange(context.TODO(), resultKey, start, stop)
+	}
+
+	if members.Err() != nil {
+		return nil, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: members.Err().Error(),
+			Message: "Failed to get items from query result on Redis",
+		}
+	}
+
+	var fetched []T
+	for _, member := range members.Val() {
+		item, errorGetItem := pg.itemCache.Get(pg.randIdOf(member))
+		if errorGetItem != nil && errorGetItem.Err == KEY_NOT_FOUND {
+			continue
+		} else if errorGetItem != nil {
+			return nil, &types.PaginationError{
+				Err:     errorGetItem.Err,
+				Details: errorGetItem.Details,
+				Message: "Failed to get item details from Redis",
+			}
+		}
+		if !deletedAt(item).IsZero() {
+			continue
+		}
+
+		fetched = append(fetched, item)
+	}
+
+	fetched, errorLive := pg.live(fetched)
+	if errorLive != nil {
+		return nil, errorLive
+	}
+
+	var items []T
+	for _, item := range fetched {
+		if processor != nil {
+			processor(item, &items)
+		} else {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+// queryKey derives the result key from the query so identical queries
+// resolve to the same set.
+func (pg *PaginationType[T]) queryKey(query types.SetQuery) string {
+	digest := sha1.Sum([]byte(fmt.Sprintf(
+		"%s|%s|%s|%v",
+		query.Operation,
+		query.Aggregate,
+		strings.Join(query.Keys, ","),
+		query.Weights,
+	)))
+
+	// the result has to share the slot of the keys it is stored from
+	if pg.hashTag {
+		return "{" + hashTagOf(query.Keys[0]) + "}" + queryKeyInfix + hex.EncodeToString(digest[:])
+	}
+
+	return namespaced(pg.namespace, pg.entityName) + queryKeyInfix + hex.EncodeToString(digest[:])
+}
+
+func (pg *PaginationType[T]) storeQuery(query types.SetQuery) (string, *types.PaginationError) {
+	if len(query.Keys) == 0 {
+		return "", &types.PaginationError{
+			Err:     INVALID_SET_QUERY,
+			Message: "Set query needs at least one key",
+		}
+	}
+	if len(query.Weights) > 0 && len(query.Weights) != len(query.Keys) {
+		return "", &types.PaginationError{
+			Err:     INVALID_SET_QUERY,
+			Message: "Set query needs one weight per key",
+		}
+	}
+
+	switch query.Operation {
+	case SET_OPERATION_INTERSECT, SET_OPERATION_UNION:
+	case SET_OPERATION_DIFF:
+		if len(query.Weights) > 0 || query.Aggregate != "" {
+			return "", &types.PaginationError{
+				Err:     INVALID_SET_QUERY,
+				Message: "Diff doesn't take weights or an aggregate",
+			}
+		}
+	default:
+		return "", &types.PaginationError{
+			Err:     INVALID_SET_QUERY,
+			Details: query.Operation,
+			Message: "Unknown set query operation",
+		}
+	}
+
+	switch query.Aggregate {
+	case "", AGGREGATE_SUM, AGGREGATE_MIN, AGGREGATE_MAX:
+	default:
+		return "", &types.PaginationError{
+			Err:     INVALID_SET_QUERY,
+			Details: query.Aggregate,
+			Message: "Unknown set query aggregate",
+		}
+	}
+
+	errorSlot := pg.validateSlot(query.Keys...)
+	if errorSlot != nil {
+		return "", errorSlot
+	}
+
+	resultKey := pg.queryKey(query)
+
+	exists := pg.redisClient.Exists(context.TODO(), resultKey)
+	if exists.Err() != nil {
+		return "", &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: exists.Err().Error(),
+			Message: "Failed to check query result on Redis",
+		}
+	}
+	if exists.Val() == 1 {
+		return resultKey, nil
+	}
+
+	store := &redis.ZStore{
+		Keys:      query.Keys,
+		Weights:   query.Weights,
+		Aggregate: strings.ToUpper(query.Aggregate),
+	}
+
+	_, errorPipeline := pg.redisClient.TxPipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		switch query.Operation {
+		case SET_OPERATION_INTERSECT:
+			pipe.ZInterStore(context.TODO(), resultKey, store)
+		case SET_OPERATION_UNION:
+			pipe.ZUnionStore(context.TODO(), resultKey, store)
+		case SET_OPERATION_DIFF:
+			pipe.ZDiffStore(context.TODO(), resultKey, query.Keys...)
+		}
+		pipe.PExpire(context.TODO(), resultKey, QUERY_RESULT_TTL)
+		return nil
+	})
+	if errorPipeline != nil {
+		return "", &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorPipeline.Error(),
+			Message: "Failed to store query result on Redis",
+		}
+	}
+
+	return resultKey, nil
+}
@@ -0,0 +1,144 @@
+package commoncrud
+
+import (
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+	mock_interfaces "github.com/lefalya/commoncrud/mocks"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	newPagination := func(redisDB redis.UniversalClient) *PaginationType[Car] {
+		return Pagination[Car](
+			"car",
+			"ranking",
+			descending,
+			[]string{"brands", "category"},
+			2,
+			"",
+			logger,
+			redisDB,
+			nil,
+		)
+	}
+
+	favourites := "user:abc:favourites:descby:ranking"
+
+	t.Run("intersect stores the result and returns the first page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		pagination := newPagination(redisDB)
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		pagination.itemCache = itemCache
+
+		query := types.SetQuery{
+			Operation: SET_OPERATION_INTERSECT,
+			Keys:      []string{mustSortedSetKey(pagination, brand, category), favourites},
+			Weights:   []float64{1, 0},
+			Aggregate: AGGREGATE_SUM,
+		}
+		resultKey := pagination.queryKey(query)
+		assert.Equal(t, key+":descby:ranking", query.Keys[0])
+
+		mockRedis.ExpectExists(resultKey).SetVal(0)
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZInterStore(resultKey, &redis.ZStore{
+			Keys:      query.Keys,
+			Weights:   query.Weights,
+			Aggregate: "SUM",
+		}).SetVal(3)
+		mockRedis.ExpectPExpire(resultKey, QUERY_RESULT_TTL).SetVal(true)
+		mockRedis.ExpectTxPipelineExec()
+		mockRedis.ExpectZRevRange(resultKey, 0, 1).SetVal([]string{"first", "second"})
+
+		itemCache.EXPECT().Get("first").Return(car, nil)
+		itemCache.EXPECT().Get("second").Return(Car{}, &types.PaginationError{Err: KEY_NOT_FOUND})
+
+		items, errorQuery := pagination.Query(query, nil, nil)
+		assert.Nil(t, errorQuery)
+		assert.Equal(t, []Car{car}, items)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("reuse an existing result and page after references", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		pagination := newPagination(redisDB)
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		pagination.itemCache = itemCache
+
+		query := types.SetQuery{
+			Operation: SET_OPERATION_UNION,
+			Keys: []string{
+				mustSortedSetKey(pagination, brand, "SUV"),
+				mustSortedSetKey(pagination, brand, "Sedan"),
+			},
+			Aggregate: AGGREGATE_MAX,
+		}
+		resultKey := pagination.queryKey(query)
+
+		mockRedis.ExpectExists(resultKey).SetVal(1)
+		mockRedis.ExpectZRevRank(resultKey, "second").RedisNil()
+		mockRedis.ExpectZRevRank(resultKey, "first").SetVal(0)
+		mockRedis.ExpectZRevRange(resultKey, 1, 2).SetVal([]string{car.GetRandId()})
+
+		itemCache.EXPECT().Get(car.GetRandId()).Return(car, nil)
+
+		items, errorQuery := pagination.Query(query, []string{"first", "second"}, nil)
+		assert.Nil(t, errorQuery)
+		assert.Equal(t, []Car{car}, items)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("drop tombstoned items with soft delete", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		pagination := newPagination(redisDB).WithSoftDelete()
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		softDeleter := mock_interfaces.NewMockSoftDeleter[Car](ctrl)
+		pagination.itemCache = softDeletingCache{itemCache, softDeleter}
+
+		tombstonedCar := NewItem(Car{Brand: brand, Category: category})
+		query := types.SetQuery{
+			Operation: SET_OPERATION_UNION,
+			Keys:      []string{mustSortedSetKey(pagination, brand, category), favourites},
+		}
+		resultKey := pagination.queryKey(query)
+
+		mockRedis.ExpectExists(resultKey).SetVal(1)
+		mockRedis.ExpectZRevRange(resultKey, 0, 1).SetVal([]string{car.GetRandId(), tombstonedCar.GetRandId()})
+
+		itemCache.EXPECT().Get(car.GetRandId()).Return(car, nil)
+		itemCache.EXPECT().Get(tombstonedCar.GetRandId()).Return(tombstonedCar, nil)
+		softDeleter.EXPECT().Tombstoned(car.GetRandId(), tombstonedCar.GetRandId()).Return([]bool{false, true}, nil)
+
+		items, errorQuery := pagination.Query(query, nil, nil)
+		assert.Nil(t, errorQuery)
+		assert.Equal(t, []Car{car}, items)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("diff rejects weights", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		pagination := newPagination(redisDB)
+
+		items, errorQuery := pagination.Query(types.SetQuery{
+			Operation: SET_OPERATION_DIFF,
+			Keys:      []string{mustSortedSetKey(pagination, brand, category), favourites},
+			Weights:   []float64{1, 1},
+		}, nil, nil)
+		assert.Nil(t, items)
+		assert.Equal(t, INVALID_SET_QUERY, errorQuery.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,142 @@
+package commoncrud
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lefalya/commoncrud/interfaces"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	reservedKeyTrailing = ":reserved"
+	// Crockford's base32, which keeps ULIDs in lexicographic time order
+	ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+var defaultRandIdGenerator = randIdGenerator([]rune(RANDID_ALPHABET), RANDID_LENGTH)
+
+// RandIdGenerator returns a generator drawing length characters of alphabet
+// uniformly from crypto/rand. alphabet must be valid UTF-8 with at least two
+// distinct characters, none repeated, and length positive.
+func RandIdGenerator(alphabet string, length int) (interfaces.IdGenerator, *types.PaginationError) {
+	if !utf8.ValidString(alphabet) {
+		return nil, &types.PaginationError{
+			Err:     INVALID_ALPHABET,
+			Details: fmt.Sprintf("%q is not valid UTF-8", alphabet),
+			Message: "Failed to build randId generator",
+		}
+	}
+
+	characters := []rune(alphabet)
+	seen := make(map[rune]bool, len(characters))
+	for _, character := range characters {
+		if seen[character] {
+			return nil, &types.PaginationError{
+				Err:     INVALID_ALPHABET,
+				Details: fmt.Sprintf("%q repeats %q", alphabet, character),
+				Message: "Failed to build randId generator",
+			}
+		}
+		seen[character] = true
+	}
+
+	if len(characters) < 2 || length <= 0 {
+		return nil, &types.PaginationError{
+			Err:     INVALID_ALPHABET,
+			Details: fmt.Sprintf("%d characters, length %d", len(characters), length),
+			Message: "Failed to build randId generator",
+		}
+	}
+
+	return randIdGenerator(characters, length), nil
+}
+
+func randIdGenerator(alphabet []rune, length int) interfaces.IdGenerator {
+	size := big.NewInt(int64(len(alphabet)))
+
+	return func() string {
+		result := make([]rune, length)
+		for i := range result {
+			index, errorRand := rand.Int(rand.Reader, size)
+			if errorRand != nil {
+				panic(errorRand)
+			}
+			result[i] = alphabet[index.Int64()]
+		}
+
+		return string(result)
+	}
+}
+
+// UUIDv7 generates a time-ordered UUID, usable as an IdGenerator.
+func UUIDv7() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// ULID generates a 26 character ULID: 48 bits of milliseconds since epoch
+// followed by 80 random bits, usable as an IdGenerator.
+func ULID() string {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], uint64(time.Now().UnixMilli())<<16)
+	if _, errorRand := rand.Read(id[6:]); errorRand != nil {
+		panic(errorRand)
+	}
+
+	// 128 bits as 26 base32 characters, the leading one carrying 3 bits
+	result := make([]byte, 26)
+	high := binary.BigEndian.Uint64(id[:8])
+	low := binary.BigEndian.Uint64(id[8:])
+	for i := 25; i >= 0; i-- {
+		result[i] = ulidAlphabet[low&0x1f]
+		low = low>>5 | high<<59
+		high >>= 5
+	}
+
+	return string(result)
+}
+
+// ReserveRandId draws randIds from generator until one is claimed with SETNX
+// within the item keyspace and no item is cached under it yet, so no two
+// items of the entity share it. The reservation is kept for ttl, or forever
+// when ttl is 0.
+func (cr *ItemCacheType[T]) ReserveRandId(generator interfaces.IdGenerator, ttl time.Duration) (string, *types.PaginationError) {
+	for attempt := 0; attempt < RANDID_RESERVE_ATTEMPTS; attempt++ {
+		randId := generator()
+		key := fmt.Sprintf(cr.itemKeyFormat, randId)
+
+		// the item and its reservation may live on different cluster slots
+		var exists *redis.IntCmd
+		var reserve *redis.BoolCmd
+		_, errorReserve := cr.redisClient.Pipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+			exists = pipe.Exists(context.TODO(), key)
+			reserve = pipe.SetNX(context.TODO(), key+reservedKeyTrailing, 1, ttl)
+			return nil
+		})
+		if errorReserve != nil {
+			return "", &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: errorReserve.Error(),
+				Message: "Failed to reserve randId on Redis",
+			}
+		}
+
+		// a reservation claimed for a cached item keeps blocking a randId
+		// that is taken anyway
+		if reserve.Val() && exists.Val() == 0 {
+			return randId, nil
+		}
+	}
+
+	return "", &types.PaginationError{
+		Err:     RANDID_EXHAUSTED,
+		Message: "Every generated randId was already taken",
+	}
+}
@@ -0,0 +1,135 @@
+package commoncrud
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandId(t *testing.T) {
+	t.Run("default alphabet and length", func(t *testing.T) {
+		randId := RandId()
+		assert.Len(t, randId, RANDID_LENGTH)
+		for _, character := range randId {
+			assert.True(t, strings.ContainsRune(RANDID_ALPHABET, character))
+		}
+	})
+
+	t.Run("configurable alphabet and length", func(t *testing.T) {
+		generator, errorGenerator := RandIdGenerator("ab", 32)
+		assert.Nil(t, errorGenerator)
+		randId := generator()
+		assert.Len(t, randId, 32)
+		assert.Equal(t, "", strings.Trim(randId, "ab"))
+	})
+
+	t.Run("multi-byte alphabet drawn by character", func(t *testing.T) {
+		generator, errorGenerator := RandIdGenerator("äöü", 8)
+		assert.Nil(t, errorGenerator)
+		randId := generator()
+		assert.Equal(t, 8, utf8.RuneCountInString(randId))
+		assert.Equal(t, "", strings.Trim(randId, "äöü"))
+	})
+
+	t.Run("reject invalid alphabets and lengths", func(t *testing.T) {
+		cases := []struct {
+			alphabet string
+			length   int
+		}{
+			{"", 16},
+			{"a", 16},
+			{"aba", 16},
+			{"\xff\xfe", 16},
+			{"ab", 0},
+		}
+		for _, c := range cases {
+			generator, errorGenerator := RandIdGenerator(c.alphabet, c.length)
+			assert.Nil(t, generator)
+			assert.Equal(t, INVALID_ALPHABET, errorGenerator.Err)
+		}
+	})
+
+	t.Run("new item draws from the given generator", func(t *testing.T) {
+		item := NewItemWith(Car{Brand: brand}, func() string { return "fixed" })
+		assert.Equal(t, "fixed", item.GetRandId())
+		assert.NotEmpty(t, item.GetUUID())
+	})
+}
+
+func TestSortableIds(t *testing.T) {
+	generators := map[string]func() string{
+		"uuid v7": UUIDv7,
+		"ulid":    ULID,
+	}
+
+	for name, generator := range generators {
+		t.Run(name+" sorts lexicographically by creation", func(t *testing.T) {
+			first := generator()
+			time.Sleep(2 * time.Millisecond)
+			second := generator()
+
+			assert.Less(t, first, second)
+		})
+	}
+
+	t.Run("ulid encoding", func(t *testing.T) {
+		ulid := ULID()
+		assert.Len(t, ulid, 26)
+		assert.Equal(t, "", strings.Trim(ulid, ulidAlphabet))
+		// the first character only carries the top 3 bits
+		assert.LessOrEqual(t, ulid[0], byte('7'))
+	})
+
+	t.Run("new sortable item", func(t *testing.T) {
+		item := NewSortableItem(Car{Brand: brand}, UUIDv7)
+		parsed, errorParse := uuid.Parse(item.GetUUID())
+		assert.Nil(t, errorParse)
+		assert.Equal(t, uuid.Version(7), parsed.Version())
+		assert.Len(t, item.GetRandId(), RANDID_LENGTH)
+	})
+}
+
+func TestReserveRandId(t *testing.T) {
+	sequence := func(randIds ...string) func() string {
+		return func() string {
+			randId := randIds[0]
+			randIds = randIds[1:]
+			return randId
+		}
+	}
+
+	t.Run("retry until an unused randId is reserved", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectExists("car:taken").SetVal(0)
+		mockRedis.ExpectSetNX("car:taken:reserved", 1, DAY).SetVal(false)
+		mockRedis.ExpectExists("car:cached").SetVal(1)
+		mockRedis.ExpectSetNX("car:cached:reserved", 1, DAY).SetVal(true)
+		mockRedis.ExpectExists("car:free").SetVal(0)
+		mockRedis.ExpectSetNX("car:free:reserved", 1, DAY).SetVal(true)
+
+		itemCache := ItemCache[Car](itemKeyFormat, logger, redisDB)
+		randId, errorReserve := itemCache.ReserveRandId(sequence("taken", "cached", "free"), DAY)
+		assert.Nil(t, errorReserve)
+		assert.Equal(t, "free", randId)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("give up after every attempt collided", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		for i := 0; i < RANDID_RESERVE_ATTEMPTS; i++ {
+			mockRedis.ExpectExists("car:taken").SetVal(0)
+			mockRedis.ExpectSetNX("car:taken:reserved", 1, time.Duration(0)).SetVal(false)
+		}
+
+		itemCache := ItemCache[Car](itemKeyFormat, logger, redisDB)
+		randId, errorReserve := itemCache.ReserveRandId(func() string { return "taken" }, 0)
+		assert.Equal(t, "", randId)
+		assert.Equal(t, RANDID_EXHAUSTED, errorReserve.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
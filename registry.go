@@ -0,0 +1,530 @@
+package commoncrud
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+
+	"github.com/lefalya/commoncrud/interfaces"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+type registeredPagination[T interfaces.Item] struct {
+	pagination *PaginationType[T]
+	fields     []int
+}
+
+// parameters reads the pagination parameters of item from its filter fields.
+func (rp registeredPagination[T]) parameters(item T) []string {
+	return fieldParameters(item, rp.fields)
+}
+
+// RegistryType keeps every pagination an entity appears in, so a single write
+// updates all of them.
+type RegistryType[T interfaces.Item] struct {
+	itemCache   interfaces.ItemCache[T]
+	paginations []registeredPagination[T]
+	logger      *slog.Logger
+	redisClient redis.UniversalClient
+}
+
+func Registry[T interfaces.Item](itemCache interfaces.ItemCache[T], logger *slog.Logger, redisClient redis.UniversalClient) *RegistryType[T] {
+	return &RegistryType[T]{
+		itemCache:   itemCache,
+		logger:      logger,
+		redisClient: redisClient,
+	}
+}
+
+// Register adds pagination to the registry. fields are the bson or db tag
+// names of the item fields holding each filterBy parameter, in order; when
+// omitted, the filterBy names are used as tag names.
+func (rg *RegistryType[T]) Register(pagination *PaginationType[T], fields ...string) *types.PaginationError {
+	if len(fields) == 0 {
+		fields = pagination.filter
+	}
+
+	if len(fields) != len(pagination.filter) {
+		return &types.PaginationError{
+			Err:     FILTER_FIELD_NOT_FOUND,
+			Details: fmt.Sprintf("expected %d filter fields, got %d", len(pagination.filter), len(fields)),
+			Message: "Filter fields don't match the pagination filters",
+		}
+	}
+
+	indexes := fieldIndexes[T](fields)
+	for i, field := range fields {
+		if indexes[i] < 0 {
+			return &types.PaginationError{
+				Err:     FILTER_FIELD_NOT_FOUND,
+				Details: field,
+				Message: "Filter field not found on item",
+			}
+		}
+	}
+
+	rg.paginations = append(rg.paginations, registeredPagination[T]{
+		pagination: pagination,
+		fields:     indexes,
+	})
+
+	return nil
+}
+
+// Add caches item and adds it to every registered pagination. Bookkeeping of
+// all sorted sets is read in one pipeline and every write goes out in a
+// single transaction.
+func (rg *RegistryType[T]) Add(item T) *types.PaginationError {
+	return rg.add(item, false)
+}
+
+// add is Add, filling the cache without write-behind when fromSource is set,
+// see sourceCache.
+func (rg *RegistryType[T]) add(item T, fromSource bool) *types.PaginationError {
+	errorHooks := beforeWrite(&item)
+	if errorHooks != nil {
+		return errorHooks
+	}
+
+	// the item cache is shared, one soft deleting pagination checks for all
+	for _, registered := range rg.paginations {
+		if registered.pagination.softDelete {
+			errorDeleted := registered.pagination.rejectDeleted(item)
+			if errorDeleted != nil {
+				return errorDeleted
+			}
+			break
+		}
+	}
+
+	errorSet := rg.setItem(item, fromSource)
+	if errorSet != nil {
+		return errorSet
+	}
+
+	keys, totalItems, bookkeepings, _, errorRead := rg.readBookkeeping(item, true)
+	if errorRead != nil {
+		return errorRead
+	}
+
+	added := make([]*redis.IntCmd, len(rg.paginations))
+	var errorPlan *types.PaginationError
+	_, errorWrite := rg.writePipelined(func(pipe redis.Pipeliner) error {
+		for i, registered := range rg.paginations {
+			pagination := registered.pagination
+			added[i], errorPlan = pagination.planAdd(pipe, keys[i], item, totalItems[i].Val(), bookkeepings[i], pagination.events(EVENT_ADD, keys[i], item))
+			if errorPlan != nil {
+				return errorPlan.Err
+			}
+		}
+		return nil
+	})
+	if errorPlan != nil {
+		return errorPlan
+	}
+	if errorWrite != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorWrite.Error(),
+			Message: "Failed to add item to pagination sets on Redis",
+		}
+	}
+
+	for i, registered := range rg.paginations {
+		if added[i] == nil || added[i].Val() > 0 {
+			errorCount := registered.pagination.countListed(keys[i], registered.parameters(item), 1)
+			if errorCount != nil {
+				return errorCount
+			}
+		}
+
+		errorTrim := registered.pagination.trimSortedSet(keys[i])
+		if errorTrim != nil {
+			return errorTrim
+		}
+	}
+
+	return nil
+}
+
+// Update caches item and refreshes it in every registered pagination set
+// listing it. The previous state of item is read from the cache: when a
+// filter attribute changed, item is moved from its old pagination set to the
+// new one in the same transaction as the other updates.
+func (rg *RegistryType[T]) Update(item T) *types.PaginationError {
+	return rg.update(item, false)
+}
+
+// update is Update, filling the cache without write-behind when fromSource
+// is set.
+func (rg *RegistryType[T]) update(item T, fromSource bool) *types.PaginationError {
+	errorHooks := beforeWrite(&item)
+	if errorHooks != nil {
+		return errorHooks
+	}
+
+	previous, errorGet := rg.itemCache.Get(item.GetRandId())
+	if errorGet != nil && errorGet.Err != KEY_NOT_FOUND {
+		return errorGet
+	}
+	hasPrevious := errorGet == nil
+
+	errorSet := rg.setItem(item, fromSource)
+	if errorSet != nil {
+		return errorSet
+	}
+
+	keys, errorKey := rg.keysOf(item)
+	if errorKey != nil {
+		return errorKey
+	}
+	var previousItemKeys []string
+	if hasPrevious {
+		previousItemKeys, errorKey = rg.keysOf(previous)
+		if errorKey != nil {
+			return errorKey
+		}
+	}
+
+	// previousKeys[i] is set only when item moves out of that pagination set
+	previousKeys := make([]string, len(rg.paginations))
+	totalItems := make([]*redis.IntCmd, len(rg.paginations))
+	bookkeepings := make([]*redis.StringCmd, len(rg.paginations))
+	previousBookkeepings := make([]*redis.StringCmd, len(rg.paginations))
+	previousMemberships := make([]membership, len(rg.paginations))
+	// ranks[i] is set when item stays in a pagination set it updates there
+	ranks := make([]*redis.IntCmd, len(rg.paginations))
+
+	_, errorRead := rg.redisClient.Pipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		for i, registered := range rg.paginations {
+			pagination := registered.pagination
+			if !hasPrevious || previousItemKeys[i] == keys[i] {
+				continue
+			}
+
+			previousKey := previousItemKeys[i]
+
+			previousKeys[i] = previousKey
+			totalItems[i] = pipe.ZCard(context.TODO(), keys[i]+pagination.sortedSetKeyTrailing)
+			if bookkeepingKey := pagination.bookkeepingKey(keys[i]); bookkeepingKey != "" {
+				bookkeepings[i] = pipe.Get(context.TODO(), bookkeepingKey)
+				previousBookkeepings[i] = pipe.Get(context.TODO(), pagination.bookkeepingKey(previousKey))
+			}
+			previousMemberships[i] = pagination.readMembership(pipe, previousKey, previous)
+		}
+		for i, registered := range rg.paginations {
+			pagination := registered.pagination
+			if previousKeys[i] != "" || (pagination.byCreation() && pagination.eventStream == "") {
+				continue
+			}
+
+			ranks[i] = pipe.ZRank(context.TODO(), keys[i]+pagination.sortedSetKeyTrailing, pagination.Member(item))
+		}
+		return nil
+	})
+	if errorRead != nil && errorRead != redis.Nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorRead.Error(),
+			Message: "Failed to read pagination sets bookkeeping on Redis",
+		}
+	}
+
+	for i, registered := range rg.paginations {
+		if previousKeys[i] == "" {
+			continue
+		}
+
+		var errorRecover *types.PaginationError
+		bookkeepings[i], errorRecover = registered.pagination.recoverBookkeeping(keys[i], totalItems[i].Val(), bookkeepings[i])
+		if errorRecover != nil {
+			return errorRecover
+		}
+	}
+
+	var boundaries []int
+	removed := make([]*redis.IntCmd, len(rg.paginations))
+	added := make([]*redis.IntCmd, len(rg.paginations))
+	var errorPlan *types.PaginationError
+	_, errorWrite := rg.writePipelined(func(pipe redis.Pipeliner) error {
+		for i, registered := range rg.paginations {
+			pagination := registered.pagination
+
+			if previousKeys[i] != "" {
+				var boundary bool
+				removed[i], boundary, errorPlan = pagination.planRemove(pipe, previousKeys[i], previous, previousMemberships[i], previousBookkeepings[i], pagination.events(EVENT_REMOVE, previousKeys[i], previous))
+				if errorPlan != nil {
+					return errorPlan.Err
+				}
+				if boundary {
+					boundaries = append(boundaries, i)
+				}
+
+				added[i], errorPlan = pagination.planAdd(pipe, keys[i], item, totalItems[i].Val(), bookkeepings[i], pagination.events(EVENT_ADD, keys[i], item))
+				if errorPlan != nil {
+					return errorPlan.Err
+				}
+				continue
+			}
+			// not listed, there's nothing to update
+			if ranks[i] == nil || ranks[i].Err() != nil {
+				continue
+			}
+
+			if !pagination.byCreation() {
+				var score float64
+				score, errorPlan = pagination.scoreOf(item)
+				if errorPlan != nil {
+					return errorPlan.Err
+				}
+
+				pipe.ZAddXX(context.TODO(), keys[i]+pagination.sortedSetKeyTrailing, redis.Z{
+					Score:  score,
+					Member: pagination.Member(item),
+				})
+				for _, component := range pagination.componentKeys(keys[i]) {
+					pipe.PExpire(context.TODO(), component, SORTED_SET_TTL)
+				}
+			}
+			for _, event := range pagination.events(EVENT_UPDATE, keys[i], item) {
+				event.add(pipe)
+			}
+		}
+		return nil
+	})
+	if errorPlan != nil {
+		return errorPlan
+	}
+	if errorWrite != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorWrite.Error(),
+			Message: "Failed to update item on pagination sets on Redis",
+		}
+	}
+
+	for _, i := range boundaries {
+		errorRecompute := rg.paginations[i].pagination.settleBoundary(previousKeys[i])
+		if errorRecompute != nil {
+			return errorRecompute
+		}
+	}
+
+	for i, registered := range rg.paginations {
+		if previousKeys[i] == "" {
+			continue
+		}
+
+		pagination := registered.pagination
+		errorCount := pagination.countRemoval(previousKeys[i], registered.parameters(previous), previous, previousMemberships[i], removed[i])
+		if errorCount != nil {
+			return errorCount
+		}
+		if added[i] == nil || added[i].Val() > 0 {
+			errorCount = pagination.countListed(keys[i], registered.parameters(item), 1)
+			if errorCount != nil {
+				return errorCount
+			}
+		}
+
+		errorTrim := pagination.trimSortedSet(keys[i])
+		if errorTrim != nil {
+			return errorTrim
+		}
+	}
+
+	return nil
+}
+
+// Remove deletes item from the cache and from every registered pagination.
+func (rg *RegistryType[T]) Remove(item T) *types.PaginationError {
+	return rg.remove(item, false)
+}
+
+// remove is Remove, evicting item without write-behind when fromSource is
+// set.
+func (rg *RegistryType[T]) remove(item T, fromSource bool) *types.PaginationError {
+	errorDelete := rg.delItem(item, fromSource)
+	if errorDelete != nil {
+		return errorDelete
+	}
+
+	keys, _, bookkeepings, memberships, errorRead := rg.readBookkeeping(item, false)
+	if errorRead != nil {
+		return errorRead
+	}
+
+	// paginations whose boundary item is being removed
+	var boundaries []int
+	removed := make([]*redis.IntCmd, len(rg.paginations))
+	var errorPlan *types.PaginationError
+	_, errorWrite := rg.writePipelined(func(pipe redis.Pipeliner) error {
+		for i, registered := range rg.paginations {
+			var boundary bool
+			pagination := registered.pagination
+			removed[i], boundary, errorPlan = pagination.planRemove(pipe, keys[i], item, memberships[i], bookkeepings[i], pagination.events(EVENT_REMOVE, keys[i], item))
+			if errorPlan != nil {
+				return errorPlan.Err
+			}
+			if boundary {
+				boundaries = append(boundaries, i)
+			}
+		}
+		return nil
+	})
+	if errorPlan != nil {
+		return errorPlan
+	}
+	if errorWrite != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorWrite.Error(),
+			Message: "Failed to remove item from pagination sets on Redis",
+		}
+	}
+
+	for _, i := range boundaries {
+		errorRecompute := rg.paginations[i].pagination.settleBoundary(keys[i])
+		if errorRecompute != nil {
+			return errorRecompute
+		}
+	}
+
+	for i, registered := range rg.paginations {
+		errorCount := registered.pagination.countRemoval(keys[i], registered.parameters(item), item, memberships[i], removed[i])
+		if errorCount != nil {
+			return errorCount
+		}
+	}
+	return nil
+}
+
+// sourceCache is implemented by caches taking writes read from the source of
+// truth without recording them for write-behind, so they aren't persisted
+// back to it.
+type sourceCache[T interfaces.Item] interface {
+	fill(item T) *types.PaginationError
+	evict(item T) *types.PaginationError
+}
+
+func (rg *RegistryType[T]) setItem(item T, fromSource bool) *types.PaginationError {
+	if cache, ok := rg.itemCache.(sourceCache[T]); ok && fromSource {
+		return cache.fill(item)
+	}
+
+	return rg.itemCache.Set(item)
+}
+
+func (rg *RegistryType[T]) delItem(item T, fromSource bool) *types.PaginationError {
+	if cache, ok := rg.itemCache.(sourceCache[T]); ok && fromSource {
+		return cache.evict(item)
+	}
+
+	return rg.itemCache.Del(item)
+}
+
+// readBookkeeping resolves the pagination key of item for every registered
+// pagination and reads, in one pipeline, the bookkeeping key of each and,
+// when withTotal is set, the size of its sorted set, recovering bookkeeping
+// like PaginationType.readBookkeeping. Otherwise item is leaving and its
+// membership is read instead.
+func (rg *RegistryType[T]) readBookkeeping(item T, withTotal bool) ([]string, []*redis.IntCmd, []*redis.StringCmd, []membership, *types.PaginationError) {
+	keys, errorKey := rg.keysOf(item)
+	if errorKey != nil {
+		return nil, nil, nil, nil, errorKey
+	}
+	totalItems := make([]*redis.IntCmd, len(rg.paginations))
+	bookkeepings := make([]*redis.StringCmd, len(rg.paginations))
+	memberships := make([]membership, len(rg.paginations))
+
+	_, errorRead := rg.redisClient.Pipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		for i, registered := range rg.paginations {
+			pagination := registered.pagination
+			if withTotal {
+				totalItems[i] = pipe.ZCard(context.TODO(), keys[i]+pagination.sortedSetKeyTrailing)
+			}
+			if bookkeepingKey := pagination.bookkeepingKey(keys[i]); bookkeepingKey != "" {
+				bookkeepings[i] = pipe.Get(context.TODO(), bookkeepingKey)
+			}
+			if !withTotal {
+				memberships[i] = pagination.readMembership(pipe, keys[i], item)
+			}
+		}
+		return nil
+	})
+	if errorRead != nil && errorRead != redis.Nil {
+		return nil, nil, nil, nil, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorRead.Error(),
+			Message: "Failed to read pagination sets bookkeeping on Redis",
+		}
+	}
+
+	if withTotal {
+		for i, registered := range rg.paginations {
+			var errorRecover *types.PaginationError
+			bookkeepings[i], errorRecover = registered.pagination.recoverBookkeeping(keys[i], totalItems[i].Val(), bookkeepings[i])
+			if errorRecover != nil {
+				return nil, nil, nil, nil, errorRecover
+			}
+		}
+	}
+
+	return keys, totalItems, bookkeepings, memberships, nil
+}
+
+// keysOf resolves the pagination key of item for every registered pagination.
+func (rg *RegistryType[T]) keysOf(item T) ([]string, *types.PaginationError) {
+	keys := make([]string, len(rg.paginations))
+	for i, registered := range rg.paginations {
+		key, errorKey := registered.pagination.baseKey(registered.parameters(item))
+		if errorKey != nil {
+			return nil, errorKey
+		}
+		keys[i] = key
+	}
+
+	return keys, nil
+}
+
+// fieldIndexes resolves the index of the item field tagged, in bson or db,
+// with each of fields, or -1 when there's none.
+func fieldIndexes[T interfaces.Item](fields []string) []int {
+	t := structTypeOf[T]()
+
+	indexes := make([]int, len(fields))
+	for i, field := range fields {
+		indexes[i] = -1
+		for j := 0; j < t.NumField(); j++ {
+			f := t.Field(j)
+			if f.Tag.Get("bson") == field || f.Tag.Get("db") == field {
+				indexes[i] = j
+				break
+			}
+		}
+	}
+
+	return indexes
+}
+
+// fieldParameters reads the pagination parameters of item from the fields at
+// indexes.
+func fieldParameters[T interfaces.Item](item T, indexes []int) []string {
+	value, ok := structValueOf(item)
+
+	parameters := make([]string, len(indexes))
+	for i, index := range indexes {
+		if !ok {
+			continue
+		}
+		field := value.Field(index)
+		if field.Kind() == reflect.Ptr && !field.IsNil() {
+			field = field.Elem()
+		}
+		parameters[i] = fmt.Sprint(field.Interface())
+	}
+
+	return parameters
+}
@@ -0,0 +1,252 @@
+package commoncrud
+
+import (
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+	mock_interfaces "github.com/lefalya/commoncrud/mocks"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	globalKey := "car" + descendingTrailing + "createdat"
+	filteredKey := key + ascendingTrailing + "ranking"
+
+	newRegistry := func(ctrl *gomock.Controller, redisDB redis.UniversalClient) (*RegistryType[Car], *mock_interfaces.MockItemCache[Car]) {
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		registry := Registry[Car](itemCache, logger, redisDB)
+
+		global := Pagination[Car]("car", "createdat", descending, nil, itemPerPage, "", logger, redisDB, nil)
+		filtered := Pagination[Car]("car", "ranking", ascending, []string{"brands", "category"}, itemPerPage, "", logger, redisDB, nil)
+
+		assert.Nil(t, registry.Register(global))
+		assert.Nil(t, registry.Register(filtered, "brand", "category"))
+
+		return registry, itemCache
+	}
+
+	t.Run("register with unknown filter field", func(t *testing.T) {
+		registry := Registry[Car](nil, logger, nil)
+		pagination := Pagination[Car]("car", "createdat", descending, []string{"brands"}, itemPerPage, "", logger, nil, nil)
+
+		errorRegister := registry.Register(pagination)
+		assert.NotNil(t, errorRegister)
+		assert.Equal(t, FILTER_FIELD_NOT_FOUND, errorRegister.Err)
+	})
+	t.Run("add item to every registered pagination", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		carImpl := car
+		carImpl.Ranking = 4
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		registry, itemCache := newRegistry(ctrl, redisDB)
+		itemCache.EXPECT().Set(carImpl).Return(nil)
+
+		mockRedis.ExpectZCard(globalKey).SetVal(3)
+		mockRedis.ExpectZCard(filteredKey).SetVal(3)
+		mockRedis.ExpectGet(filteredKey + ":highestscore").SetVal("10")
+
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZAdd(globalKey, redis.Z{
+			Score:  float64(carImpl.GetCreatedAt().UnixMilli()),
+			Member: carImpl.GetRandId(),
+		}).SetVal(1)
+		mockRedis.ExpectPExpire(globalKey, SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectPExpire(globalKey+":settled", SORTED_SET_TTL).SetVal(false)
+		mockRedis.ExpectZAdd(filteredKey, redis.Z{
+			Score:  float64(carImpl.Ranking),
+			Member: carImpl.GetRandId(),
+		}).SetVal(1)
+		mockRedis.ExpectPExpire(filteredKey, SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectPExpire(filteredKey+":settled", SORTED_SET_TTL).SetVal(false)
+		mockRedis.ExpectPExpire(filteredKey+":highestscore", SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectTxPipelineExec()
+
+		errorAdd := registry.Add(carImpl)
+		assert.Nil(t, errorAdd)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("update item score in custom attribute paginations", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		carImpl := car
+		carImpl.Ranking = 7
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		registry, itemCache := newRegistry(ctrl, redisDB)
+		itemCache.EXPECT().Get(carImpl.GetRandId()).Return(car, nil)
+		itemCache.EXPECT().Set(carImpl).Return(nil)
+
+		mockRedis.ExpectZRank(filteredKey, carImpl.GetRandId()).SetVal(0)
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZAddXX(filteredKey, redis.Z{
+			Score:  float64(carImpl.Ranking),
+			Member: carImpl.GetRandId(),
+		}).SetVal(0)
+		mockRedis.ExpectPExpire(filteredKey, SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectPExpire(filteredKey+":settled", SORTED_SET_TTL).SetVal(false)
+		mockRedis.ExpectPExpire(filteredKey+":highestscore", SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectTxPipelineExec()
+
+		errorUpdate := registry.Update(carImpl)
+		assert.Nil(t, errorUpdate)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("update skips paginations the item isn't listed in", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		carImpl := car
+		carImpl.Ranking = 7
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		registry, itemCache := newRegistry(ctrl, redisDB)
+		registry.paginations[0].pagination.WithEvents("car:events", 0)
+		itemCache.EXPECT().Get(carImpl.GetRandId()).Return(car, nil)
+		itemCache.EXPECT().Set(carImpl).Return(nil)
+
+		mockRedis.ExpectZRank(globalKey, carImpl.GetRandId()).SetVal(0)
+		mockRedis.ExpectZRank(filteredKey, carImpl.GetRandId()).RedisNil()
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectXAdd(&redis.XAddArgs{
+			Stream: "car:events",
+			Values: []interface{}{
+				"operation", EVENT_UPDATE,
+				"entity", "car",
+				"randid", carImpl.GetRandId(),
+				"key", globalKey,
+				"version", carImpl.GetUpdatedAt().UnixMilli(),
+			},
+		}).SetVal("1-0")
+		mockRedis.ExpectTxPipelineExec()
+
+		errorUpdate := registry.Update(carImpl)
+		assert.Nil(t, errorUpdate)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("update moves item when a filter attribute changes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		previous := car
+		previous.Ranking = 4
+		previous.Category = "Sedan"
+		previousKey := mustConcatKey(paginationKeyFormat, []string{brand, "Sedan"}) + ascendingTrailing + "ranking"
+
+		carImpl := car
+		carImpl.Ranking = 4
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		registry, itemCache := newRegistry(ctrl, redisDB)
+		itemCache.EXPECT().Get(carImpl.GetRandId()).Return(previous, nil)
+		itemCache.EXPECT().Set(carImpl).Return(nil)
+
+		mockRedis.ExpectZCard(filteredKey).SetVal(3)
+		mockRedis.ExpectGet(filteredKey + ":highestscore").SetVal("10")
+		mockRedis.ExpectGet(previousKey + ":highestscore").SetVal("10")
+		expectMembership(mockRedis, previousKey, carImpl.GetRandId(), 1, ascending, redis.Z{Score: 10, Member: "highest"})
+
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZRem(previousKey, carImpl.GetRandId()).SetVal(1)
+		mockRedis.ExpectZAdd(filteredKey, redis.Z{
+			Score:  float64(carImpl.Ranking),
+			Member: carImpl.GetRandId(),
+		}).SetVal(1)
+		mockRedis.ExpectPExpire(filteredKey, SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectPExpire(filteredKey+":settled", SORTED_SET_TTL).SetVal(false)
+		mockRedis.ExpectPExpire(filteredKey+":highestscore", SORTED_SET_TTL).SetVal(true)
+		mockRedis.ExpectTxPipelineExec()
+
+		errorUpdate := registry.Update(carImpl)
+		assert.Nil(t, errorUpdate)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("remove item from every registered pagination", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		carImpl := car
+		carImpl.Ranking = 4
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		registry, itemCache := newRegistry(ctrl, redisDB)
+		itemCache.EXPECT().Del(carImpl).Return(nil)
+
+		expectMembership(mockRedis, globalKey, carImpl.GetRandId(), 0, descending, redis.Z{Score: 1, Member: "oldest"})
+		mockRedis.ExpectGet(filteredKey + ":highestscore").SetVal("10")
+		expectMembership(mockRedis, filteredKey, carImpl.GetRandId(), 1, ascending, redis.Z{Score: 10, Member: "highest"})
+
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZRem(globalKey, carImpl.GetRandId()).SetVal(1)
+		mockRedis.ExpectZRem(filteredKey, carImpl.GetRandId()).SetVal(1)
+		mockRedis.ExpectTxPipelineExec()
+
+		errorRemove := registry.Remove(carImpl)
+		assert.Nil(t, errorRemove)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("remove publishes the events of every registered pagination", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		carImpl := car
+		carImpl.Ranking = 4
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		registry, itemCache := newRegistry(ctrl, redisDB)
+		registry.paginations[0].pagination.WithEvents("car:events", 0)
+		itemCache.EXPECT().Del(carImpl).Return(nil)
+
+		expectMembership(mockRedis, globalKey, carImpl.GetRandId(), 0, descending, redis.Z{Score: 1, Member: "oldest"})
+		mockRedis.ExpectGet(filteredKey + ":highestscore").SetVal("10")
+		expectMembership(mockRedis, filteredKey, carImpl.GetRandId(), 1, ascending, redis.Z{Score: 10, Member: "highest"})
+
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZRem(globalKey, carImpl.GetRandId()).SetVal(1)
+		mockRedis.ExpectXAdd(&redis.XAddArgs{
+			Stream: "car:events",
+			Values: []interface{}{
+				"operation", EVENT_REMOVE,
+				"entity", "car",
+				"randid", carImpl.GetRandId(),
+				"key", globalKey,
+				"version", carImpl.GetUpdatedAt().UnixMilli(),
+			},
+		}).SetVal("1-0")
+		mockRedis.ExpectZRem(filteredKey, carImpl.GetRandId()).SetVal(1)
+		mockRedis.ExpectTxPipelineExec()
+
+		errorRemove := registry.Remove(carImpl)
+		assert.Nil(t, errorRemove)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("remove skips paginations the item isn't listed in", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		carImpl := car
+		carImpl.Ranking = 4
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		registry, itemCache := newRegistry(ctrl, redisDB)
+		registry.paginations[1].pagination.WithEvents("car:events", 0)
+		itemCache.EXPECT().Del(carImpl).Return(nil)
+
+		expectMembership(mockRedis, globalKey, carImpl.GetRandId(), 0, descending, redis.Z{Score: 1, Member: "oldest"})
+		mockRedis.ExpectGet(filteredKey + ":highestscore").SetVal("10")
+		expectMembership(mockRedis, filteredKey, carImpl.GetRandId(), -1, ascending, redis.Z{Score: 10, Member: "highest"})
+
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectZRem(globalKey, carImpl.GetRandId()).SetVal(1)
+		mockRedis.ExpectTxPipelineExec()
+
+		errorRemove := registry.Remove(carImpl)
+		assert.Nil(t, errorRemove)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,145 @@
+package commoncrud
+
+import (
+	"time"
+
+	"github.com/lefalya/commoncrud/interfaces"
+	"github.com/lefalya/commoncrud/types"
+)
+
+// WithSoftDelete makes AddItem, Registry.Add and seeders refuse items
+// carrying DeletedAt or a live tombstone, so a reseed cannot resurrect them,
+// and drops them from Query results. The item cache must implement
+// interfaces.SoftDeleter to check tombstones.
+func (pg *PaginationType[T]) WithSoftDelete() *PaginationType[T] {
+	pg.softDelete = true
+	return pg
+}
+
+// SoftRemoveItem tombstones item and takes it out of the pagination set.
+func (pg *PaginationType[T]) SoftRemoveItem(item T, paginationParameters ...string) *types.PaginationError {
+	key, errorKey := pg.baseKey(paginationParameters)
+	if errorKey != nil {
+		return errorKey
+	}
+
+	softDeleter, errorSoftDeleter := pg.softDeleter()
+	if errorSoftDeleter != nil {
+		return errorSoftDeleter
+	}
+
+	errorDelete := softDeleter.SoftDel(item)
+	if errorDelete != nil {
+		return errorDelete
+	}
+
+	return pg.unlist(key, item, paginationParameters)
+}
+
+// RestoreItem brings a soft deleted item back into the pagination set.
+func (pg *PaginationType[T]) RestoreItem(randId string, paginationParameters ...string) (T, *types.PaginationError) {
+	var nilItem T
+
+	softDeleter, errorSoftDeleter := pg.softDeleter()
+	if errorSoftDeleter != nil {
+		return nilItem, errorSoftDeleter
+	}
+
+	item, errorRestore := softDeleter.Restore(randId)
+	if errorRestore != nil {
+		return nilItem, errorRestore
+	}
+
+	errorAdd := pg.AddItem(item, paginationParameters...)
+	if errorAdd != nil {
+		return nilItem, errorAdd
+	}
+
+	return item, nil
+}
+
+func (pg *PaginationType[T]) softDeleter() (interfaces.SoftDeleter[T], *types.PaginationError) {
+	softDeleter, ok := pg.itemCache.(interfaces.SoftDeleter[T])
+	if !ok {
+		return nil, &types.PaginationError{
+			Err:     SOFT_DELETE_UNSUPPORTED,
+			Message: "Item cache doesn't keep tombstones",
+		}
+	}
+
+	return softDeleter, nil
+}
+
+func (pg *PaginationType[T]) rejectDeleted(item T) *types.PaginationError {
+	if !pg.softDelete {
+		return nil
+	}
+
+	live, errorLive := pg.live([]T{item})
+	if errorLive != nil {
+		return errorLive
+	}
+	if len(live) == 0 {
+		return &types.PaginationError{
+			Err:     ITEM_DELETED,
+			Message: "Refused to add a deleted item",
+		}
+	}
+
+	return nil
+}
+
+// live drops the items carrying DeletedAt or a live tombstone, checking every
+// tombstone in one round trip. Without WithSoftDelete items are kept as is.
+func (pg *PaginationType[T]) live(items []T) ([]T, *types.PaginationError) {
+	if !pg.softDelete || len(items) == 0 {
+		return items, nil
+	}
+
+	var candidates []T
+	var randIds []string
+	for _, item := range items {
+		if deletedAt(item).IsZero() {
+			candidates = append(candidates, item)
+			randIds = append(randIds, item.GetRandId())
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	softDeleter, errorSoftDeleter := pg.softDeleter()
+	if errorSoftDeleter != nil {
+		return nil, errorSoftDeleter
+	}
+
+	tombstoned, errorTombstoned := softDeleter.Tombstoned(randIds...)
+	if errorTombstoned != nil {
+		return nil, errorTombstoned
+	}
+
+	var kept []T
+	for i, item := range candidates {
+		if !tombstoned[i] {
+			kept = append(kept, item)
+		}
+	}
+
+	return kept, nil
+}
+
+// deletedAt reads DeletedAt off item, zero when item doesn't track deletion.
+func deletedAt[T interfaces.Item](item T) time.Time {
+	if deletable, ok := any(item).(interfaces.Deletable); ok {
+		return deletable.GetDeletedAt()
+	}
+
+	return time.Time{}
+}
+
+// setDeletedAt stamps DeletedAt on item, when it tracks deletion.
+func setDeletedAt[T interfaces.Item](item T, at time.Time) {
+	if deletable, ok := any(item).(interfaces.Deletable); ok {
+		deletable.SetDeletedAt(at)
+	}
+}
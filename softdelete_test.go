@@ -0,0 +1,226 @@
+package commoncrud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+	"github.com/lefalya/commoncrud/interfaces"
+	mock_interfaces "github.com/lefalya/commoncrud/mocks"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// softDeletingCache is an item cache keeping tombstones.
+type softDeletingCache struct {
+	*mock_interfaces.MockItemCache[Car]
+	*mock_interfaces.MockSoftDeleter[Car]
+}
+
+func TestSoftDelete(t *testing.T) {
+	newPagination := func(ctrl *gomock.Controller) (*PaginationType[Car], *mock_interfaces.MockSoftDeleter[Car], redismock.ClientMock) {
+		softDeleter := mock_interfaces.NewMockSoftDeleter[Car](ctrl)
+		itemCache := softDeletingCache{mock_interfaces.NewMockItemCache[Car](ctrl), softDeleter}
+		redisDB, mockRedis := redismock.NewClientMock()
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			itemCache,
+		).WithSoftDelete()
+
+		return pagination, softDeleter, mockRedis
+	}
+
+	t.Run("tombstone and restore an item", func(t *testing.T) {
+		deletedCar := NewItem(Car{Brand: brand, Category: category})
+		deletedCar.SetDeletedAt(time.Now().Truncate(time.Millisecond))
+		itemKey := "car:" + deletedCar.GetRandId()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		itemCache := ItemCache[Car](itemKeyFormat, logger, redisDB)
+
+		tombstone, errorEncode := itemCache.encode(deletedCar)
+		assert.Nil(t, errorEncode)
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectSet(itemKey+":tombstone", string(tombstone), TOMBSTONE_TTL).SetVal("OK")
+		mockRedis.ExpectDel(itemKey).SetVal(1)
+		mockRedis.ExpectTxPipelineExec()
+
+		assert.Nil(t, itemCache.SoftDel(deletedCar))
+
+		restoredCar, _ := itemCache.decode(string(tombstone))
+		restoredCar.SetDeletedAt(time.Time{})
+		restored, errorEncode := itemCache.encode(restoredCar)
+		assert.Nil(t, errorEncode)
+		mockRedis.ExpectGet(itemKey + ":tombstone").SetVal(string(tombstone))
+		mockRedis.ExpectSet(itemKey, string(restored), INDIVIDUAL_KEY_TTL).SetVal("OK")
+		mockRedis.ExpectDel(itemKey + ":tombstone").SetVal(1)
+
+		item, errorRestore := itemCache.Restore(deletedCar.GetRandId())
+		assert.Nil(t, errorRestore)
+		assert.True(t, item.GetDeletedAt().IsZero())
+		assert.Equal(t, brand, item.Brand)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("stamp the deletion in UTC", func(t *testing.T) {
+		deletedCar := NewItem(Car{Brand: brand, Category: category})
+		itemKey := "car:" + deletedCar.GetRandId()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectTxPipeline()
+		mockRedis.Regexp().ExpectSet(itemKey+":tombstone", `.*`, TOMBSTONE_TTL).SetVal("OK")
+		mockRedis.ExpectDel(itemKey).SetVal(1)
+		mockRedis.ExpectTxPipelineExec()
+
+		assert.Nil(t, ItemCache[Car](itemKeyFormat, logger, redisDB).SoftDel(deletedCar))
+		assert.False(t, deletedCar.GetDeletedAt().IsZero())
+		assert.Equal(t, time.UTC, deletedCar.GetDeletedAt().Location())
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("restore past the tombstone ttl", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet("car:gone:tombstone").RedisNil()
+
+		_, errorRestore := ItemCache[Car](itemKeyFormat, logger, redisDB).Restore("gone")
+		assert.Equal(t, KEY_NOT_FOUND, errorRestore.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("refuse to add a tombstoned item", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		pagination, softDeleter, mockRedis := newPagination(ctrl)
+		softDeleter.EXPECT().Tombstoned(car.GetRandId()).Return([]bool{true}, nil)
+
+		errorAddItem := pagination.AddItem(car, brand, category)
+		assert.Equal(t, ITEM_DELETED, errorAddItem.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("soft remove unlists the item", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		pagination, softDeleter, mockRedis := newPagination(ctrl)
+		softDeleter.EXPECT().SoftDel(car).Return(nil)
+		expectMembership(mockRedis, key+descendingTrailing+"createdat", car.GetRandId(), 0, descending, redis.Z{Score: 1, Member: "oldest"})
+		mockRedis.ExpectZRem(key+descendingTrailing+"createdat", car.GetRandId()).SetVal(1)
+
+		assert.Nil(t, pagination.SoftRemoveItem(car, brand, category))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("soft remove without a tombstone keeping cache", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			mock_interfaces.NewMockItemCache[Car](ctrl),
+		).WithSoftDelete()
+
+		errorRemove := pagination.SoftRemoveItem(car, brand, category)
+		assert.Equal(t, SOFT_DELETE_UNSUPPORTED, errorRemove.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("check tombstones in one round trip", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectExists("car:a:tombstone").SetVal(0)
+		mockRedis.ExpectExists("car:b:tombstone").SetVal(1)
+
+		tombstoned, errorTombstoned := ItemCache[Car](itemKeyFormat, logger, redisDB).Tombstoned("a", "b")
+		assert.Nil(t, errorTombstoned)
+		assert.Equal(t, []bool{false, true}, tombstoned)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("seeder drops deleted items before writing them", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		deletedCar := NewItem(Car{Brand: brand})
+		deletedCar.SetDeletedAt(time.Now())
+		tombstonedCar := NewItem(Car{Brand: brand})
+		pagination, softDeleter, mockRedis := newPagination(ctrl)
+		softDeleter.EXPECT().Tombstoned(car.GetRandId(), tombstonedCar.GetRandId()).Return([]bool{false, true}, nil)
+
+		var written []Car
+		items, errorSeed := pagination.SeedOnce(func(_ int64, live interfaces.ItemFilter[Car]) ([]Car, *types.PaginationError) {
+			kept, errorLive := live([]Car{car, deletedCar, tombstonedCar})
+			if errorLive != nil {
+				return nil, errorLive
+			}
+			written = kept
+			return kept, nil
+		}, brand, category)
+		assert.Nil(t, errorSeed)
+		assert.Equal(t, []Car{car}, written)
+		assert.Equal(t, []Car{car}, items)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("fetch linked leaves deleted items out", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		deletedCar := NewItem(Car{Brand: brand})
+		deletedCar.SetDeletedAt(time.Now())
+		tombstonedCar := NewItem(Car{Brand: brand})
+		pagination, softDeleter, mockRedis := newPagination(ctrl)
+		itemCache := pagination.itemCache.(softDeletingCache).MockItemCache
+		itemCache.EXPECT().Get(car.GetRandId()).Return(car, nil)
+		itemCache.EXPECT().Get(deletedCar.GetRandId()).Return(deletedCar, nil)
+		itemCache.EXPECT().Get(tombstonedCar.GetRandId()).Return(tombstonedCar, nil)
+		softDeleter.EXPECT().Tombstoned(car.GetRandId(), tombstonedCar.GetRandId()).Return([]bool{false, true}, nil)
+
+		sortedSetKey := key + descendingTrailing + "createdat"
+		mockRedis.ExpectExists(sortedSetKey + ":settled").SetVal(1)
+		mockRedis.ExpectZCard(sortedSetKey).SetVal(3)
+		mockRedis.ExpectZRevRange(sortedSetKey, 0, itemPerPage-1).SetVal([]string{
+			car.GetRandId(),
+			deletedCar.GetRandId(),
+			tombstonedCar.GetRandId(),
+		})
+
+		items, errorFetch := pagination.FetchLinked(nil, nil, nil, brand, category)
+		assert.Nil(t, errorFetch)
+		assert.Equal(t, []Car{car}, items)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("registry refuses to add a tombstoned item", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		pagination, softDeleter, mockRedis := newPagination(ctrl)
+		softDeleter.EXPECT().Tombstoned(car.GetRandId()).Return([]bool{true}, nil)
+
+		registry := Registry[Car](mock_interfaces.NewMockItemCache[Car](ctrl), logger, nil)
+		assert.Nil(t, registry.Register(pagination, "brand", "category"))
+
+		errorAdd := registry.Add(car)
+		assert.Equal(t, ITEM_DELETED, errorAdd.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+}
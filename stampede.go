@@ -0,0 +1,143 @@
+package commoncrud
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/lefalya/commoncrud/interfaces"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	seedLockKeyTrailing  = ":seedlock"
+	seedFenceKeyTrailing = ":seedlock:fence"
+)
+
+// releaseSeedLock only deletes the lock when it still holds our fencing
+// token, so a holder whose lock already expired can't release a newer one.
+var releaseSeedLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type seedResult[T interfaces.Item] struct {
+	items []T
+	err   *types.PaginationError
+}
+
+// WithSeedLock enables a distributed lock around SeedOnce so only one process
+// reseeds a pagination set at a time. Processes that lose the race wait up to
+// wait for the holder to finish before giving up with SEED_IN_PROGRESS. The
+// fencing counter expires SEED_FENCE_TTL after the last acquisition, or ttl
+// when longer.
+func (pg *PaginationType[T]) WithSeedLock(ttl time.Duration, wait time.Duration) *PaginationType[T] {
+	pg.seedLockTTL = ttl
+	pg.seedLockWait = wait
+	return pg
+}
+
+// SeedOnce runs seeder for the given pagination set, coalescing concurrent
+// calls within this process into a single run. With WithSeedLock enabled the
+// run is also guarded by a Redis lock shared across processes. seeder is
+// handed a filter dropping soft deleted items, see WithSoftDelete, to run
+// the loaded items through before writing them.
+//
+// When another process holds the lock and finishes within the wait window,
+// SeedOnce returns no items and no error; the set is then available from
+// cache. When it doesn't, SEED_IN_PROGRESS is returned so the caller can serve
+// stale data instead.
+func (pg *PaginationType[T]) SeedOnce(seeder interfaces.SeedFunc[T], paginationParameters ...string) ([]T, *types.PaginationError) {
+	key, errorKey := pg.SortedSetKey(paginationParameters...)
+	if errorKey != nil {
+		return nil, errorKey
+	}
+
+	result, _, _ := pg.seedGroup.Do(key, func() (interface{}, error) {
+		items, errorSeed := pg.seedWithLock(key, seeder)
+		return seedResult[T]{items: items, err: errorSeed}, nil
+	})
+
+	seeded := result.(seedResult[T])
+	return seeded.items, seeded.err
+}
+
+func (pg *PaginationType[T]) seedWithLock(key string, seeder interfaces.SeedFunc[T]) ([]T, *types.PaginationError) {
+	if pg.seedLockTTL <= 0 {
+		return seeder(0, pg.live)
+	}
+
+	lockKey := key + seedLockKeyTrailing
+
+	fenceTTL := SEED_FENCE_TTL
+	if pg.seedLockTTL > fenceTTL {
+		fenceTTL = pg.seedLockTTL
+	}
+
+	var fence *redis.IntCmd
+	_, errorFence := pg.redisClient.TxPipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		fence = pipe.Incr(context.TODO(), key+seedFenceKeyTrailing)
+		pipe.PExpire(context.TODO(), key+seedFenceKeyTrailing, fenceTTL)
+		return nil
+	})
+	if errorFence != nil {
+		return nil, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorFence.Error(),
+			Message: "Failed to generate seed lock fencing token on Redis",
+		}
+	}
+	token := strconv.FormatInt(fence.Val(), 10)
+
+	acquireLock := pg.redisClient.SetNX(context.TODO(), lockKey, token, pg.seedLockTTL)
+	if acquireLock.Err() != nil {
+		return nil, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: acquireLock.Err().Error(),
+			Message: "Failed to acquire seed lock on Redis",
+		}
+	}
+
+	if !acquireLock.Val() {
+		return nil, pg.waitSeedLock(lockKey)
+	}
+
+	items, errorSeed := seeder(fence.Val(), pg.live)
+
+	release := releaseSeedLock.Run(context.TODO(), pg.redisClient, []string{lockKey}, token)
+	if release.Err() != nil && pg.logger != nil {
+		// the lock will expire on its own, the seed result is still valid
+		pg.logger.Error("failed to release seed lock", "key", lockKey, "error", release.Err().Error())
+	}
+
+	return items, errorSeed
+}
+
+func (pg *PaginationType[T]) waitSeedLock(lockKey string) *types.PaginationError {
+	deadline := time.Now().Add(pg.seedLockWait)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(SEED_LOCK_POLL_INTERVAL)
+
+		lockExists := pg.redisClient.Exists(context.TODO(), lockKey)
+		if lockExists.Err() != nil {
+			return &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: lockExists.Err().Error(),
+				Message: "Failed to check seed lock on Redis",
+			}
+		}
+
+		if lockExists.Val() == 0 {
+			return nil
+		}
+	}
+
+	return &types.PaginationError{
+		Err:     SEED_IN_PROGRESS,
+		Message: "Pagination set is being seeded by another process",
+	}
+}
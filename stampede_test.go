@@ -0,0 +1,119 @@
+package commoncrud
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/lefalya/commoncrud/interfaces"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeedOnce(t *testing.T) {
+	sortedSetKey := key + descendingTrailing + "createdat"
+
+	t.Run("concurrent calls are coalesced into a single seed", func(t *testing.T) {
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			nil,
+			nil,
+		)
+
+		var calls int32
+		release := make(chan struct{})
+		seeder := func(fencingToken int64, live interfaces.ItemFilter[Car]) ([]Car, *types.PaginationError) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return []Car{car}, nil
+		}
+
+		var wg sync.WaitGroup
+		results := make([][]Car, 5)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _ = pagination.SeedOnce(seeder, brand, category)
+			}(i)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		for _, items := range results {
+			assert.Equal(t, []Car{car}, items)
+		}
+	})
+	t.Run("seed while holding the distributed lock", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectIncr(sortedSetKey + seedFenceKeyTrailing).SetVal(7)
+		mockRedis.ExpectPExpire(sortedSetKey+seedFenceKeyTrailing, SEED_FENCE_TTL).SetVal(true)
+		mockRedis.ExpectTxPipelineExec()
+		mockRedis.ExpectSetNX(sortedSetKey+seedLockKeyTrailing, "7", time.Second).SetVal(true)
+		mockRedis.ExpectEvalSha(releaseSeedLock.Hash(), []string{sortedSetKey + seedLockKeyTrailing}, "7").SetVal(int64(1))
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		).WithSeedLock(time.Second, 0)
+
+		var receivedToken int64
+		items, errorSeed := pagination.SeedOnce(func(fencingToken int64, live interfaces.ItemFilter[Car]) ([]Car, *types.PaginationError) {
+			receivedToken = fencingToken
+			return []Car{car}, nil
+		}, brand, category)
+
+		assert.Nil(t, errorSeed)
+		assert.Equal(t, []Car{car}, items)
+		assert.Equal(t, int64(7), receivedToken)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("lock held by another process", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectTxPipeline()
+		mockRedis.ExpectIncr(sortedSetKey + seedFenceKeyTrailing).SetVal(8)
+		mockRedis.ExpectPExpire(sortedSetKey+seedFenceKeyTrailing, SEED_FENCE_TTL).SetVal(true)
+		mockRedis.ExpectTxPipelineExec()
+		mockRedis.ExpectSetNX(sortedSetKey+seedLockKeyTrailing, "8", time.Second).SetVal(false)
+
+		pagination := Pagination[Car](
+			"car",
+			"createdat",
+			descending,
+			[]string{"brands", "category"},
+			itemPerPage,
+			"",
+			logger,
+			redisDB,
+			nil,
+		).WithSeedLock(time.Second, 0)
+
+		items, errorSeed := pagination.SeedOnce(func(fencingToken int64, live interfaces.ItemFilter[Car]) ([]Car, *types.PaginationError) {
+			t.Fatal("seeder must not run without the lock")
+			return nil, nil
+		}, brand, category)
+
+		assert.Nil(t, items)
+		assert.NotNil(t, errorSeed)
+		assert.Equal(t, SEED_IN_PROGRESS, errorSeed.Err)
+	})
+}
@@ -0,0 +1,175 @@
+package commoncrud
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+
+	"github.com/lefalya/commoncrud/interfaces"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Operations of a change stream, named after MongoDB's operationType.
+	CHANGE_INSERT     = "insert"
+	CHANGE_UPDATE     = "update"
+	CHANGE_REPLACE    = "replace"
+	CHANGE_DELETE     = "delete"
+	CHANGE_INVALIDATE = "invalidate"
+)
+
+type SyncerType[T interfaces.Item] struct {
+	registry    *RegistryType[T]
+	open        interfaces.ChangeStreamOpener[T]
+	tokenKey    string
+	logger      *slog.Logger
+	redisClient redis.UniversalClient
+}
+
+// Syncer keeps the items and paginations of registry in step with the source
+// of truth by applying the changes of the streams open returns. The resume
+// token of the last applied change is stored at tokenKey, so Run picks up
+// where the previous run stopped. Changes are applied at least once: a crash
+// between a write and the token update replays that change.
+func Syncer[T interfaces.Item](
+	registry *RegistryType[T],
+	open interfaces.ChangeStreamOpener[T],
+	tokenKey string,
+	logger *slog.Logger,
+	redisClient redis.UniversalClient,
+) *SyncerType[T] {
+	return &SyncerType[T]{
+		registry:    registry,
+		open:        open,
+		tokenKey:    tokenKey,
+		logger:      logger,
+		redisClient: redisClient,
+	}
+}
+
+// Run applies changes until ctx is cancelled or the stream fails. A change
+// that can't be applied stops the run without moving the resume token, so
+// the next run retries it.
+func (sy *SyncerType[T]) Run(ctx context.Context) *types.PaginationError {
+	resumeToken, errorToken := sy.ResumeToken()
+	if errorToken != nil {
+		return errorToken
+	}
+
+	stream, errorOpen := sy.open(ctx, resumeToken)
+	if errorOpen != nil {
+		return &types.PaginationError{
+			Err:     CHANGE_STREAM_FATAL_ERROR,
+			Details: errorOpen.Error(),
+			Message: "Failed to open change stream",
+		}
+	}
+	defer stream.Close(context.TODO())
+
+	for stream.Next(ctx) {
+		change, errorDecode := stream.Current()
+		if errorDecode != nil {
+			return &types.PaginationError{
+				Err:     CHANGE_STREAM_FATAL_ERROR,
+				Details: errorDecode.Error(),
+				Message: "Failed to decode change",
+			}
+		}
+
+		errorApply := sy.Apply(change)
+		if errorApply != nil {
+			return errorApply
+		}
+
+		saveToken := sy.redisClient.Set(context.TODO(), sy.tokenKey, change.ResumeToken, 0)
+		if saveToken.Err() != nil {
+			return &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: saveToken.Err().Error(),
+				Message: "Failed to save resume token on Redis",
+			}
+		}
+	}
+
+	if ctx.Err() == nil && stream.Err() != nil {
+		return &types.PaginationError{
+			Err:     CHANGE_STREAM_FATAL_ERROR,
+			Details: stream.Err().Error(),
+			Message: "Change stream failed",
+		}
+	}
+
+	return nil
+}
+
+// Apply maps change to the registry: inserts are added, updates and
+// replacements refreshed, and deletions removed using the cached state of
+// the item, since that is what its pagination sets were built from. An
+// update without its document, deleted before it could be looked up, is
+// applied as a deletion of RandId, or skipped when RandId is unknown too.
+// Changes already come from the source of truth, so they aren't recorded
+// for write-behind.
+func (sy *SyncerType[T]) Apply(change types.SourceChange[T]) *types.PaginationError {
+	switch change.Operation {
+	case CHANGE_INSERT:
+		return sy.registry.add(change.Item, true)
+	case CHANGE_UPDATE, CHANGE_REPLACE:
+		if reflect.ValueOf(&change.Item).Elem().IsZero() {
+			if change.RandId == "" {
+				if sy.logger != nil {
+					sy.logger.Warn("update without document nor randId skipped")
+				}
+				return nil
+			}
+			return sy.remove(change.RandId)
+		}
+		return sy.registry.update(change.Item, true)
+	case CHANGE_DELETE:
+		if change.RandId == "" {
+			if sy.logger != nil {
+				sy.logger.Warn("deletion without randId skipped, enable pre-images on the collection")
+			}
+			return nil
+		}
+		return sy.remove(change.RandId)
+	case CHANGE_INVALIDATE:
+		return &types.PaginationError{
+			Err:     CHANGE_STREAM_INVALIDATED,
+			Message: "Collection was dropped or renamed, resync it from scratch",
+		}
+	}
+
+	return nil
+}
+
+// remove unlists the cached state of the item randId, if any.
+func (sy *SyncerType[T]) remove(randId string) *types.PaginationError {
+	previous, errorGet := sy.registry.itemCache.Get(randId)
+	if errorGet != nil {
+		if errorGet.Err == KEY_NOT_FOUND {
+			// nothing cached, nothing to unlist
+			return nil
+		}
+		return errorGet
+	}
+
+	return sy.registry.remove(previous, true)
+}
+
+// ResumeToken returns the stored resume token, or nil when none was saved.
+func (sy *SyncerType[T]) ResumeToken() ([]byte, *types.PaginationError) {
+	token := sy.redisClient.Get(context.TODO(), sy.tokenKey)
+	if token.Err() != nil {
+		if token.Err() == redis.Nil {
+			return nil, nil
+		}
+		return nil, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: token.Err().Error(),
+			Message: "Failed to get resume token on Redis",
+		}
+	}
+
+	return []byte(token.Val()), nil
+}
@@ -0,0 +1,191 @@
+package commoncrud
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+	"github.com/lefalya/commoncrud/interfaces"
+	mock_interfaces "github.com/lefalya/commoncrud/mocks"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncer(t *testing.T) {
+	tokenKey := "car:resumetoken"
+
+	newSyncer := func(ctrl *gomock.Controller, redisDB redis.UniversalClient, stream interfaces.ChangeStream[Car], expectedToken []byte) (*SyncerType[Car], *mock_interfaces.MockItemCache[Car]) {
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		registry := Registry[Car](itemCache, logger, redisDB)
+
+		open := func(ctx context.Context, resumeToken []byte) (interfaces.ChangeStream[Car], error) {
+			assert.Equal(t, expectedToken, resumeToken)
+			return stream, nil
+		}
+
+		return Syncer[Car](registry, open, tokenKey, logger, redisDB), itemCache
+	}
+
+	t.Run("resume from the stored token and save it after every change", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		stream := mock_interfaces.NewMockChangeStream[Car](ctrl)
+		gomock.InOrder(
+			stream.EXPECT().Next(gomock.Any()).Return(true),
+			stream.EXPECT().Current().Return(types.SourceChange[Car]{
+				Operation:   CHANGE_INSERT,
+				RandId:      car.GetRandId(),
+				Item:        car,
+				ResumeToken: []byte("token-2"),
+			}, nil),
+			stream.EXPECT().Next(gomock.Any()).Return(false),
+			stream.EXPECT().Err().Return(nil),
+			stream.EXPECT().Close(gomock.Any()).Return(nil),
+		)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(tokenKey).SetVal("token-1")
+		mockRedis.ExpectSet(tokenKey, []byte("token-2"), 0).SetVal("OK")
+
+		syncer, itemCache := newSyncer(ctrl, redisDB, stream, []byte("token-1"))
+		itemCache.EXPECT().Set(car).Return(nil)
+
+		assert.Nil(t, syncer.Run(context.TODO()))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("keep the token of a change that failed to apply", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		stream := mock_interfaces.NewMockChangeStream[Car](ctrl)
+		stream.EXPECT().Next(gomock.Any()).Return(true)
+		stream.EXPECT().Current().Return(types.SourceChange[Car]{
+			Operation:   CHANGE_UPDATE,
+			RandId:      car.GetRandId(),
+			Item:        car,
+			ResumeToken: []byte("token-2"),
+		}, nil)
+		stream.EXPECT().Close(gomock.Any()).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(tokenKey).RedisNil()
+
+		syncer, itemCache := newSyncer(ctrl, redisDB, stream, nil)
+		itemCache.EXPECT().Get(car.GetRandId()).Return(Car{}, &types.PaginationError{Err: REDIS_FATAL_ERROR})
+
+		errorRun := syncer.Run(context.TODO())
+		assert.Equal(t, REDIS_FATAL_ERROR, errorRun.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("stream failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		stream := mock_interfaces.NewMockChangeStream[Car](ctrl)
+		stream.EXPECT().Next(gomock.Any()).Return(false)
+		stream.EXPECT().Err().Return(errors.New("connection reset")).Times(2)
+		stream.EXPECT().Close(gomock.Any()).Return(nil)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectGet(tokenKey).RedisNil()
+
+		syncer, _ := newSyncer(ctrl, redisDB, stream, nil)
+
+		errorRun := syncer.Run(context.TODO())
+		assert.Equal(t, CHANGE_STREAM_FATAL_ERROR, errorRun.Err)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("delete unlists the cached item", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		syncer, itemCache := newSyncer(ctrl, redisDB, nil, nil)
+		itemCache.EXPECT().Get(car.GetRandId()).Return(car, nil)
+		itemCache.EXPECT().Del(car).Return(nil)
+
+		assert.Nil(t, syncer.Apply(types.SourceChange[Car]{Operation: CHANGE_DELETE, RandId: car.GetRandId()}))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("delete of an uncached item", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, _ := redismock.NewClientMock()
+		syncer, itemCache := newSyncer(ctrl, redisDB, nil, nil)
+		itemCache.EXPECT().Get("gone").Return(Car{}, &types.PaginationError{Err: KEY_NOT_FOUND})
+
+		assert.Nil(t, syncer.Apply(types.SourceChange[Car]{Operation: CHANGE_DELETE, RandId: "gone"}))
+	})
+
+	t.Run("update without document unlists the cached item", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		syncer, itemCache := newSyncer(ctrl, redisDB, nil, nil)
+		itemCache.EXPECT().Get(car.GetRandId()).Return(car, nil)
+		itemCache.EXPECT().Del(car).Return(nil)
+
+		assert.Nil(t, syncer.Apply(types.SourceChange[Car]{Operation: CHANGE_UPDATE, RandId: car.GetRandId()}))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("update without document nor randId is skipped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		syncer, _ := newSyncer(ctrl, redisDB, nil, nil)
+
+		assert.Nil(t, syncer.Apply(types.SourceChange[Car]{Operation: CHANGE_REPLACE}))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("skipped changes don't need a logger", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		syncer := Syncer[Car](Registry[Car](nil, nil, redisDB), nil, tokenKey, nil, redisDB)
+
+		assert.Nil(t, syncer.Apply(types.SourceChange[Car]{Operation: CHANGE_REPLACE}))
+		assert.Nil(t, syncer.Apply(types.SourceChange[Car]{Operation: CHANGE_DELETE}))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("applied changes aren't marked dirty for write-behind", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		itemCache := ItemCache[Car](itemKeyFormat, logger, redisDB)
+		WriteBehind[Car](itemCache, nil, 10, logger, redisDB)
+		syncer := Syncer[Car](Registry[Car](itemCache, logger, redisDB), nil, tokenKey, logger, redisDB)
+
+		carInByte, errorEncode := itemCache.encode(car)
+		assert.Nil(t, errorEncode)
+
+		mockRedis.Regexp().ExpectSet("car:"+car.GetRandId(), `.*`, INDIVIDUAL_KEY_TTL).SetVal("OK")
+		mockRedis.ExpectGet("car:" + car.GetRandId()).SetVal(string(carInByte))
+		mockRedis.ExpectExpire("car:"+car.GetRandId(), INDIVIDUAL_KEY_TTL).SetVal(true)
+		mockRedis.ExpectDel("car:" + car.GetRandId()).SetVal(1)
+
+		assert.Nil(t, syncer.Apply(types.SourceChange[Car]{Operation: CHANGE_INSERT, RandId: car.GetRandId(), Item: car}))
+		assert.Nil(t, syncer.Apply(types.SourceChange[Car]{Operation: CHANGE_DELETE, RandId: car.GetRandId()}))
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+
+	t.Run("invalidated stream", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, _ := redismock.NewClientMock()
+		syncer, _ := newSyncer(ctrl, redisDB, nil, nil)
+
+		errorApply := syncer.Apply(types.SourceChange[Car]{Operation: CHANGE_INVALIDATE})
+		assert.Equal(t, CHANGE_STREAM_INVALIDATED, errorApply.Err)
+	})
+}
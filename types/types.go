@@ -1,7 +1,57 @@
 package types
 
+import "time"
+
 type PaginationError struct {
 	Err     error
 	Details string
 	Message string
 }
+
+type WriteBehindStats struct {
+	// Pending is the number of dirty items waiting to be persisted.
+	Pending int64
+	// Lag is the age of the oldest pending write.
+	Lag time.Duration
+	// DeadLettered is the number of writes and deletions given up on after
+	// all retries.
+	DeadLettered int64
+	Flushed      uint64
+	Failed       uint64
+	Retries      uint64
+}
+
+// SetQuery combines pagination sorted sets into one result set. Keys are
+// full sorted set keys, see PaginationType.SortedSetKey. Weights and
+// Aggregate ("sum", "min" or "max") don't apply to "diff".
+type SetQuery struct {
+	Operation string
+	Keys      []string
+	Weights   []float64
+	Aggregate string
+}
+
+// ChangeEvent is a write published to a change stream. PaginationKey is the
+// sorted set key of list events and empty for item events; Version is the
+// item's UpdatedAt in Unix milliseconds, so consumers can drop stale events.
+type ChangeEvent struct {
+	// ID is the stream entry ID, set on events read by a consumer.
+	ID            string
+	Operation     string
+	Entity        string
+	RandId        string
+	PaginationKey string
+	Version       int64
+}
+
+// SourceChange is a change read from the source of truth. Item is the
+// document after the change; for deletions it is the document before the
+// change when the source provides it, and only RandId is set otherwise.
+// Updates to a document deleted since carry no Item, only RandId when the
+// source knows it.
+type SourceChange[T any] struct {
+	Operation   string
+	RandId      string
+	Item        T
+	ResumeToken []byte
+}
@@ -0,0 +1,414 @@
+package commoncrud
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lefalya/commoncrud/interfaces"
+	"github.com/lefalya/commoncrud/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	dirtyKeyName          = "writebehind:dirty"
+	deadLetterKeyTrailing = ":deadletter"
+	// deletedMemberPrefix marks dirty set members recording a deletion
+	deletedMemberPrefix = "deleted:"
+)
+
+// clearDirty removes flushed members from the dirty set, skipping members
+// whose score changed because they were written again during the flush.
+var clearDirty = redis.NewScript(`
+local removed = 0
+for i = 1, #ARGV, 2 do
+	local score = redis.call("ZSCORE", KEYS[1], ARGV[i])
+	if score and tonumber(score) == tonumber(ARGV[i + 1]) then
+		removed = removed + redis.call("ZREM", KEYS[1], ARGV[i])
+	end
+end
+return removed
+`)
+
+type WriteBehindType[T interfaces.Item] struct {
+	itemCache   interfaces.ItemCache[T]
+	source      *ItemCacheType[T]
+	persister   interfaces.Persister[T]
+	batchSize   int64
+	maxRetries  int
+	backoff     time.Duration
+	logger      *slog.Logger
+	redisClient redis.UniversalClient
+	flushed     atomic.Uint64
+	failed      atomic.Uint64
+	retries     atomic.Uint64
+}
+
+// WriteBehind switches itemCache to write-behind mode: every Set and Del is
+// recorded in a dirty set on Redis, and Flush/Run hand the dirty items to
+// persister in batches of batchSize.
+func WriteBehind[T interfaces.Item](
+	itemCache *ItemCacheType[T],
+	persister interfaces.Persister[T],
+	batchSize int64,
+	logger *slog.Logger,
+	redisClient redis.UniversalClient,
+) *WriteBehindType[T] {
+	itemCache.dirtyKey = fmt.Sprintf(itemCache.itemKeyFormat, dirtyKeyName)
+
+	return &WriteBehindType[T]{
+		itemCache:   itemCache,
+		source:      itemCache,
+		persister:   persister,
+		batchSize:   batchSize,
+		maxRetries:  WRITE_BEHIND_MAX_RETRIES,
+		backoff:     WRITE_BEHIND_BACKOFF,
+		logger:      logger,
+		redisClient: redisClient,
+	}
+}
+
+// dirtyKey is the dirty set of the item cache WriteBehind was built for.
+func (wb *WriteBehindType[T]) dirtyKey() string {
+	return wb.source.dirtyKey
+}
+
+// WithRetry overrides how many times a failed batch is retried and the
+// initial backoff, which doubles on every attempt.
+func (wb *WriteBehindType[T]) WithRetry(maxRetries int, backoff time.Duration) *WriteBehindType[T] {
+	wb.maxRetries = maxRetries
+	wb.backoff = backoff
+	return wb
+}
+
+// tombstoneReader is implemented by caches keeping soft deleted items, whose
+// tombstone is persisted in place of the item.
+type tombstoneReader[T interfaces.Item] interface {
+	tombstone(randId string) (T, *types.PaginationError)
+}
+
+// markDirty records randId in the dirty set for the write-behind worker, as a
+// write or as a deletion, replacing a pending record of the other kind so
+// only the latest one is flushed.
+func (cr *ItemCacheType[T]) markDirty(randId string, deleted bool) *types.PaginationError {
+	if cr.dirtyKey == "" {
+		return nil
+	}
+
+	member, stale := randId, deletedMemberPrefix+randId
+	if deleted {
+		member, stale = stale, member
+	}
+
+	_, errorPipeline := cr.redisClient.Pipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		pipe.ZRem(context.TODO(), cr.dirtyKey, stale)
+		pipe.ZAdd(context.TODO(), cr.dirtyKey, redis.Z{
+			Score:  float64(time.Now().UnixMilli()),
+			Member: member,
+		})
+		return nil
+	})
+	if errorPipeline != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: errorPipeline.Error(),
+			Message: "Failed to mark item dirty for write-behind",
+		}
+	}
+
+	return nil
+}
+
+// Flush persists one batch of the oldest dirty items, deleting those removed
+// from the cache, and returns how many were flushed. Writes or deletions that
+// still fail after all retries are moved to the dead letter set, so they don't
+// hold back the rest of the queue; see RequeueDeadLetters.
+func (wb *WriteBehindType[T]) Flush() (int, *types.PaginationError) {
+	members := wb.redisClient.ZRangeWithScores(context.TODO(), wb.dirtyKey(), 0, wb.batchSize-1)
+	if members.Err() != nil {
+		return 0, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: members.Err().Error(),
+			Message: "Failed to get dirty items from Redis",
+		}
+	}
+
+	if len(members.Val()) == 0 {
+		return 0, nil
+	}
+
+	var items []T
+	var deletedIds []string
+	// member and score pairs, as clearDirty takes them
+	var skippedMembers, itemMembers, deletedMembers []interface{}
+	for _, member := range members.Val() {
+		pair := []interface{}{member.Member, strconv.FormatFloat(member.Score, 'f', -1, 64)}
+
+		randId, deleted := strings.CutPrefix(member.Member.(string), deletedMemberPrefix)
+		if deleted {
+			deletedIds = append(deletedIds, randId)
+			deletedMembers = append(deletedMembers, pair...)
+			continue
+		}
+
+		item, errorGet := wb.get(randId)
+		if errorGet != nil {
+			if errorGet.Err == KEY_NOT_FOUND {
+				// expired before it could be flushed, nothing left to persist
+				if wb.logger != nil {
+					wb.logger.Warn("dirty item no longer cached", "randId", randId)
+				}
+				skippedMembers = append(skippedMembers, pair...)
+				continue
+			}
+			return 0, errorGet
+		}
+
+		items = append(items, item)
+		itemMembers = append(itemMembers, pair...)
+	}
+
+	var flushed int
+	var errorFlush *types.PaginationError
+	var failedMembers []interface{}
+	clearedMembers := skippedMembers
+
+	if len(items) > 0 {
+		errorPersist := wb.retry(len(items), func() error {
+			return wb.persister.Persist(items)
+		})
+		if errorPersist != nil {
+			errorFlush = errorPersist
+			failedMembers = append(failedMembers, itemMembers...)
+		} else {
+			flushed += len(items)
+			clearedMembers = append(clearedMembers, itemMembers...)
+		}
+	}
+
+	if len(deletedIds) > 0 {
+		errorDelete := wb.retry(len(deletedIds), func() error {
+			return wb.persister.Delete(deletedIds)
+		})
+		if errorDelete != nil {
+			errorFlush = errorDelete
+			failedMembers = append(failedMembers, deletedMembers...)
+		} else {
+			flushed += len(deletedIds)
+			clearedMembers = append(clearedMembers, deletedMembers...)
+		}
+	}
+
+	errorDeadLetter := wb.deadLetter(failedMembers)
+	if errorDeadLetter != nil {
+		return 0, errorDeadLetter
+	}
+
+	if len(clearedMembers) > 0 {
+		clearFlushed := clearDirty.Run(context.TODO(), wb.redisClient, []string{wb.dirtyKey()}, clearedMembers...)
+		if clearFlushed.Err() != nil {
+			return 0, &types.PaginationError{
+				Err:     REDIS_FATAL_ERROR,
+				Details: clearFlushed.Err().Error(),
+				Message: "Failed to clear flushed items from dirty set",
+			}
+		}
+	}
+
+	wb.flushed.Add(uint64(flushed))
+	return flushed, errorFlush
+}
+
+// get reads a dirty item, falling back to its tombstone once soft deleted.
+func (wb *WriteBehindType[T]) get(randId string) (T, *types.PaginationError) {
+	item, errorGet := wb.itemCache.Get(randId)
+	if errorGet == nil || errorGet.Err != KEY_NOT_FOUND {
+		return item, errorGet
+	}
+
+	if reader, ok := wb.itemCache.(tombstoneReader[T]); ok {
+		return reader.tombstone(randId)
+	}
+
+	return item, errorGet
+}
+
+// deadLetter moves members, member and score pairs, from the dirty set to
+// the dead letter set. Members written again meanwhile stay dirty as well.
+func (wb *WriteBehindType[T]) deadLetter(members []interface{}) *types.PaginationError {
+	if len(members) == 0 {
+		return nil
+	}
+
+	deadLetters := make([]redis.Z, 0, len(members)/2)
+	for i := 0; i < len(members); i += 2 {
+		score, _ := strconv.ParseFloat(members[i+1].(string), 64)
+		deadLetters = append(deadLetters, redis.Z{Score: score, Member: members[i]})
+	}
+
+	addDeadLetters := wb.redisClient.ZAdd(context.TODO(), wb.dirtyKey()+deadLetterKeyTrailing, deadLetters...)
+	if addDeadLetters.Err() != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: addDeadLetters.Err().Error(),
+			Message: "Failed to move failed items to dead letter set",
+		}
+	}
+
+	clearFailed := clearDirty.Run(context.TODO(), wb.redisClient, []string{wb.dirtyKey()}, members...)
+	if clearFailed.Err() != nil {
+		return &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: clearFailed.Err().Error(),
+			Message: "Failed to clear failed items from dirty set",
+		}
+	}
+
+	return nil
+}
+
+// RequeueDeadLetters moves every dead lettered write or deletion back to the
+// dirty set and returns how many were requeued. Items written again since
+// keep their newer record.
+func (wb *WriteBehindType[T]) RequeueDeadLetters() (int, *types.PaginationError) {
+	deadLetterKey := wb.dirtyKey() + deadLetterKeyTrailing
+
+	deadLetters := wb.redisClient.ZRangeWithScores(context.TODO(), deadLetterKey, 0, -1)
+	if deadLetters.Err() != nil {
+		return 0, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: deadLetters.Err().Error(),
+			Message: "Failed to get dead letters from Redis",
+		}
+	}
+
+	if len(deadLetters.Val()) == 0 {
+		return 0, nil
+	}
+
+	members := make([]interface{}, 0, len(deadLetters.Val()))
+	for _, deadLetter := range deadLetters.Val() {
+		members = append(members, deadLetter.Member)
+	}
+
+	requeue := wb.redisClient.ZAddNX(context.TODO(), wb.dirtyKey(), deadLetters.Val()...)
+	if requeue.Err() != nil {
+		return 0, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: requeue.Err().Error(),
+			Message: "Failed to requeue dead letters on Redis",
+		}
+	}
+
+	removeDeadLetters := wb.redisClient.ZRem(context.TODO(), deadLetterKey, members...)
+	if removeDeadLetters.Err() != nil {
+		return 0, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: removeDeadLetters.Err().Error(),
+			Message: "Failed to remove requeued dead letters on Redis",
+		}
+	}
+
+	return len(members), nil
+}
+
+// retry runs write, a Persist or Delete of count items, retrying with
+// backoff until it succeeds or maxRetries is exhausted.
+func (wb *WriteBehindType[T]) retry(count int, write func() error) *types.PaginationError {
+	backoff := wb.backoff
+
+	for attempt := 0; ; attempt++ {
+		errorWrite := write()
+		if errorWrite == nil {
+			return nil
+		}
+
+		if attempt >= wb.maxRetries {
+			wb.failed.Add(uint64(count))
+			return &types.PaginationError{
+				Err:     PERSIST_FATAL_ERROR,
+				Details: errorWrite.Error(),
+				Message: "Failed to persist dirty items after retries",
+			}
+		}
+
+		wb.retries.Add(1)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// Run flushes dirty items until ctx is cancelled, draining the dirty set and
+// then sleeping for interval between passes.
+func (wb *WriteBehindType[T]) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			flushed, errorFlush := wb.Flush()
+			if errorFlush != nil {
+				if wb.logger != nil {
+					wb.logger.Error("write-behind flush failed", "error", errorFlush.Err.Error(), "details", errorFlush.Details)
+				}
+				break
+			}
+			if flushed == 0 {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stats reports the write-behind backlog and the counters of this worker.
+func (wb *WriteBehindType[T]) Stats() (types.WriteBehindStats, *types.PaginationError) {
+	stats := types.WriteBehindStats{
+		Flushed: wb.flushed.Load(),
+		Failed:  wb.failed.Load(),
+		Retries: wb.retries.Load(),
+	}
+
+	pending := wb.redisClient.ZCard(context.TODO(), wb.dirtyKey())
+	if pending.Err() != nil {
+		return stats, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: pending.Err().Error(),
+			Message: "Failed to count dirty items on Redis",
+		}
+	}
+	stats.Pending = pending.Val()
+
+	deadLettered := wb.redisClient.ZCard(context.TODO(), wb.dirtyKey()+deadLetterKeyTrailing)
+	if deadLettered.Err() != nil {
+		return stats, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: deadLettered.Err().Error(),
+			Message: "Failed to count dead letters on Redis",
+		}
+	}
+	stats.DeadLettered = deadLettered.Val()
+
+	oldest := wb.redisClient.ZRangeWithScores(context.TODO(), wb.dirtyKey(), 0, 0)
+	if oldest.Err() != nil {
+		return stats, &types.PaginationError{
+			Err:     REDIS_FATAL_ERROR,
+			Details: oldest.Err().Error(),
+			Message: "Failed to get oldest dirty item on Redis",
+		}
+	}
+	if len(oldest.Val()) > 0 {
+		stats.Lag = time.Since(time.UnixMilli(int64(oldest.Val()[0].Score)))
+	}
+
+	return stats, nil
+}
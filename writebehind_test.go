@@ -0,0 +1,216 @@
+package commoncrud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/golang/mock/gomock"
+	mock_interfaces "github.com/lefalya/commoncrud/mocks"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBehind(t *testing.T) {
+	dirtyKey := "car:" + dirtyKeyName
+
+	t.Run("set marks item dirty", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		itemCache := ItemCache[Car](itemKeyFormat, logger, redisDB)
+		WriteBehind[Car](itemCache, nil, 10, logger, redisDB)
+
+		mockRedis.Regexp().ExpectSet("car:"+car.GetRandId(), `.*`, INDIVIDUAL_KEY_TTL).SetVal("OK")
+		mockRedis.ExpectZRem(dirtyKey, deletedMemberPrefix+car.GetRandId()).SetVal(0)
+		mockRedis.CustomMatch(func(expected, actual []interface{}) error {
+			// the score is the write time, only the key and member are stable
+			if actual[1] != dirtyKey || actual[3] != car.GetRandId() {
+				return errors.New("unexpected dirty set member")
+			}
+			return nil
+		}).ExpectZAdd(dirtyKey, redis.Z{Member: car.GetRandId()}).SetVal(1)
+
+		errorSet := itemCache.Set(car)
+		assert.Nil(t, errorSet)
+		assert.Equal(t, dirtyKey, itemCache.dirtyKey)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("del marks item deleted", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		itemCache := ItemCache[Car](itemKeyFormat, logger, redisDB)
+		WriteBehind[Car](itemCache, nil, 10, logger, redisDB)
+
+		mockRedis.ExpectDel("car:" + car.GetRandId()).SetVal(1)
+		mockRedis.ExpectZRem(dirtyKey, car.GetRandId()).SetVal(1)
+		mockRedis.CustomMatch(func(expected, actual []interface{}) error {
+			if actual[1] != dirtyKey || actual[3] != deletedMemberPrefix+car.GetRandId() {
+				return errors.New("unexpected dirty set member")
+			}
+			return nil
+		}).ExpectZAdd(dirtyKey, redis.Z{Member: deletedMemberPrefix + car.GetRandId()}).SetVal(1)
+
+		errorDel := itemCache.Del(car)
+		assert.Nil(t, errorDel)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("loader fills aren't marked dirty", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		itemCache := ItemCache[Car](itemKeyFormat, logger, redisDB).
+			WithLoader(func(ctx context.Context, randId string) (Car, error) {
+				return car, nil
+			}, time.Minute)
+		WriteBehind[Car](itemCache, nil, 10, logger, redisDB)
+
+		mockRedis.ExpectGet("car:" + car.GetRandId()).RedisNil()
+		mockRedis.ExpectExists("car:" + car.GetRandId() + negativeKeyTrailing).SetVal(0)
+		mockRedis.ExpectExists("car:" + car.GetRandId() + tombstoneKeyTrailing).SetVal(0)
+		mockRedis.ExpectDel("car:" + car.GetRandId() + negativeKeyTrailing).SetVal(0)
+		mockRedis.Regexp().ExpectSet("car:"+car.GetRandId(), `.*`, INDIVIDUAL_KEY_TTL).SetVal("OK")
+
+		_, errorGet := itemCache.Get(car.GetRandId())
+		assert.Nil(t, errorGet)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("flush persists dirty items", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZRangeWithScores(dirtyKey, 0, 9).SetVal([]redis.Z{
+			{Score: 1700000000000, Member: car.GetRandId()},
+		})
+		mockRedis.ExpectEvalSha(clearDirty.Hash(), []string{dirtyKey}, car.GetRandId(), "1700000000000").SetVal(int64(1))
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Get(car.GetRandId()).Return(car, nil)
+
+		persister := mock_interfaces.NewMockPersister[Car](ctrl)
+		persister.EXPECT().Persist([]Car{car}).Return(nil)
+
+		writeBehind := WriteBehind[Car](ItemCache[Car](itemKeyFormat, logger, redisDB), persister, 10, logger, redisDB)
+		writeBehind.itemCache = itemCache
+
+		flushed, errorFlush := writeBehind.Flush()
+		assert.Nil(t, errorFlush)
+		assert.Equal(t, 1, flushed)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+		assert.Equal(t, uint64(1), writeBehind.flushed.Load())
+	})
+	t.Run("flush deletes items removed from the cache", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZRangeWithScores(dirtyKey, 0, 9).SetVal([]redis.Z{
+			{Score: 1700000000000, Member: deletedMemberPrefix + car.GetRandId()},
+		})
+		mockRedis.ExpectEvalSha(clearDirty.Hash(), []string{dirtyKey}, deletedMemberPrefix+car.GetRandId(), "1700000000000").SetVal(int64(1))
+
+		persister := mock_interfaces.NewMockPersister[Car](ctrl)
+		persister.EXPECT().Delete([]string{car.GetRandId()}).Return(nil)
+
+		writeBehind := WriteBehind[Car](ItemCache[Car](itemKeyFormat, logger, redisDB), persister, 10, logger, redisDB)
+		writeBehind.itemCache = mock_interfaces.NewMockItemCache[Car](ctrl)
+
+		flushed, errorFlush := writeBehind.Flush()
+		assert.Nil(t, errorFlush)
+		assert.Equal(t, 1, flushed)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("flush persists the tombstone of soft deleted items", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		deleted := NewItem(Car{Brand: brand, Category: category})
+		deleted.SetDeletedAt(deleted.GetUpdatedAt())
+		tombstone, errorMarshal := json.Marshal(deleted)
+		assert.Nil(t, errorMarshal)
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZRangeWithScores(dirtyKey, 0, 9).SetVal([]redis.Z{
+			{Score: 1700000000000, Member: deleted.GetRandId()},
+		})
+		mockRedis.ExpectGet("car:" + deleted.GetRandId()).RedisNil()
+		mockRedis.ExpectGet("car:" + deleted.GetRandId() + tombstoneKeyTrailing).SetVal(string(tombstone))
+		mockRedis.ExpectEvalSha(clearDirty.Hash(), []string{dirtyKey}, deleted.GetRandId(), "1700000000000").SetVal(int64(1))
+
+		persister := mock_interfaces.NewMockPersister[Car](ctrl)
+		persister.EXPECT().Persist(gomock.Any()).DoAndReturn(func(items []Car) error {
+			assert.Equal(t, deleted.GetRandId(), items[0].GetRandId())
+			assert.False(t, items[0].GetDeletedAt().IsZero())
+			return nil
+		})
+
+		writeBehind := WriteBehind[Car](ItemCache[Car](itemKeyFormat, logger, redisDB), persister, 10, logger, redisDB)
+
+		flushed, errorFlush := writeBehind.Flush()
+		assert.Nil(t, errorFlush)
+		assert.Equal(t, 1, flushed)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("flush dead letters items after retries are exhausted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		redisDB, mockRedis := redismock.NewClientMock()
+		mockRedis.ExpectZRangeWithScores(dirtyKey, 0, 9).SetVal([]redis.Z{
+			{Score: 1700000000000, Member: car.GetRandId()},
+		})
+		mockRedis.ExpectZAdd(dirtyKey+deadLetterKeyTrailing, redis.Z{
+			Score:  1700000000000,
+			Member: car.GetRandId(),
+		}).SetVal(1)
+		mockRedis.ExpectEvalSha(clearDirty.Hash(), []string{dirtyKey}, car.GetRandId(), "1700000000000").SetVal(int64(1))
+
+		itemCache := mock_interfaces.NewMockItemCache[Car](ctrl)
+		itemCache.EXPECT().Get(car.GetRandId()).Return(car, nil)
+
+		persister := mock_interfaces.NewMockPersister[Car](ctrl)
+		persister.EXPECT().Persist([]Car{car}).Return(errors.New("database unavailable")).Times(3)
+
+		writeBehind := WriteBehind[Car](ItemCache[Car](itemKeyFormat, logger, redisDB), persister, 10, logger, redisDB).
+			WithRetry(2, time.Millisecond)
+		writeBehind.itemCache = itemCache
+
+		flushed, errorFlush := writeBehind.Flush()
+		assert.NotNil(t, errorFlush)
+		assert.Equal(t, PERSIST_FATAL_ERROR, errorFlush.Err)
+		assert.Equal(t, 0, flushed)
+		assert.Equal(t, uint64(2), writeBehind.retries.Load())
+		assert.Equal(t, uint64(1), writeBehind.failed.Load())
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("requeue dead letters", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		deadLetter := redis.Z{Score: 1700000000000, Member: car.GetRandId()}
+		mockRedis.ExpectZRangeWithScores(dirtyKey+deadLetterKeyTrailing, 0, -1).SetVal([]redis.Z{deadLetter})
+		mockRedis.ExpectZAddNX(dirtyKey, deadLetter).SetVal(1)
+		mockRedis.ExpectZRem(dirtyKey+deadLetterKeyTrailing, car.GetRandId()).SetVal(1)
+
+		writeBehind := WriteBehind[Car](ItemCache[Car](itemKeyFormat, logger, redisDB), nil, 10, logger, redisDB)
+
+		requeued, errorRequeue := writeBehind.RequeueDeadLetters()
+		assert.Nil(t, errorRequeue)
+		assert.Equal(t, 1, requeued)
+		assert.Nil(t, mockRedis.ExpectationsWereMet())
+	})
+	t.Run("stats reports pending items and lag", func(t *testing.T) {
+		redisDB, mockRedis := redismock.NewClientMock()
+		oldest := time.Now().Add(-time.Minute)
+		mockRedis.ExpectZCard(dirtyKey).SetVal(4)
+		mockRedis.ExpectZCard(dirtyKey + deadLetterKeyTrailing).SetVal(1)
+		mockRedis.ExpectZRangeWithScores(dirtyKey, 0, 0).SetVal([]redis.Z{
+			{Score: float64(oldest.UnixMilli()), Member: car.GetRandId()},
+		})
+
+		writeBehind := WriteBehind[Car](ItemCache[Car](itemKeyFormat, logger, redisDB), nil, 10, logger, redisDB)
+
+		stats, errorStats := writeBehind.Stats()
+		assert.Nil(t, errorStats)
+		assert.Equal(t, int64(4), stats.Pending)
+		assert.Equal(t, int64(1), stats.DeadLettered)
+		assert.GreaterOrEqual(t, stats.Lag, time.Minute)
+	})
+}